@@ -3,6 +3,7 @@ package adapter
 import (
 	"io"
 	"net/http"
+	"time"
 )
 
 // Adapter defines the interface for an Adapter implementation
@@ -11,6 +12,7 @@ type Adapter interface {
 	Stop(reason error) error
 	Status() Status
 	WriteLog(io.Writer)
+	LogLines() []LogLine
 	ServeHTTP(w http.ResponseWriter, r *http.Request)
 }
 
@@ -22,6 +24,8 @@ const (
 	StatusStarting Status = "starting"
 	// StatusRunning is the successful running state of the adapter
 	StatusRunning Status = "running"
+	// StatusUnhealthy is the state when a running adapter fails health checks
+	StatusUnhealthy Status = "unhealthy"
 	// StatusStopping is the state when an adapter is stopping
 	StatusStopping Status = "stopping"
 	// StatusStopped is the state when an adapter has been stopped
@@ -29,3 +33,43 @@ const (
 	// StatusError is the state when an error has occurred
 	StatusError Status = "error"
 )
+
+// LogLine is a single line of output from an adapter
+type LogLine struct {
+	Time    time.Time
+	Process string
+	Stream  string
+	Text    string
+}
+
+const (
+	// StreamStdout tags lines written to standard output
+	StreamStdout = "stdout"
+	// StreamStderr tags lines written to standard error
+	StreamStderr = "stderr"
+)
+
+// ProcessStatus describes one of the extra processes an adapter supervises
+// alongside the web process
+type ProcessStatus struct {
+	Name       string
+	Command    string
+	Pid        int
+	Status     string
+	Restarts   int
+	KilledPids []int `json:",omitempty"`
+}
+
+// ProcessMetrics is a sample of the resources used by an app's process tree.
+// PSS is the proportional set size, memory shared between processes is split
+// between them rather than counted in full for each one as it is in RSS
+type ProcessMetrics struct {
+	Time       time.Time
+	Processes  int
+	CPUTime    time.Duration
+	CPUPercent float64
+	RSS        int64
+	PSS        int64
+	FDs        int
+	Threads    int
+}
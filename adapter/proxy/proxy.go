@@ -66,3 +66,6 @@ func (a *adapter) Command() *exec.Cmd { return nil }
 
 // WriteLog doesn't do anything
 func (a *adapter) WriteLog(w io.Writer) {}
+
+// LogLines doesn't do anything
+func (a *adapter) LogLines() []zadapter.LogLine { return nil }
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	zadapter "github.com/moomerman/zap/adapter"
+	"github.com/vektra/errors"
+)
+
+// appEnvMarker is set in the environment of every app process to the app
+// host so orphaned processes can be recognised after zapd restarts
+const appEnvMarker = "ZAP_APP"
+
+// RunState is what's needed to find the processes of a running app again
+// after zapd restarts
+type RunState struct {
+	Pid     int
+	Port    string
+	Socket  string `json:",omitempty"`
+	Workers []int  `json:",omitempty"`
+}
+
+// RunState returns the pids and port or socket of the app, or an empty state if the
+// app isn't running
+func (a *adapter) RunState() RunState {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.pgid == 0 || a.state == zadapter.StatusStopping || a.state == zadapter.StatusStopped {
+		return RunState{}
+	}
+
+	state := RunState{Pid: a.pgid, Port: a.Port, Socket: a.Socket}
+	for _, w := range a.Workers {
+		if status := w.status(); status.Pid != 0 {
+			state.Workers = append(state.Workers, status.Pid)
+		}
+	}
+	return state
+}
+
+// startSpooled starts the command with its output written to files in the
+// spool dir rather than pipes, so it keeps running if zapd goes away and can
+// be adopted again
+func (a *adapter) startSpooled(cmd *exec.Cmd) error {
+	if err := os.MkdirAll(a.SpoolDir, 0755); err != nil {
+		return errors.Context(err, "creating spool dir")
+	}
+
+	stdoutPath, stderrPath := a.spoolPaths()
+
+	stdout, err := os.OpenFile(stdoutPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND|os.O_TRUNC, 0644)
+	if err != nil {
+		return errors.Context(err, "opening spool")
+	}
+	defer stdout.Close()
+
+	stderr, err := os.OpenFile(stderrPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND|os.O_TRUNC, 0644)
+	if err != nil {
+		return errors.Context(err, "opening spool")
+	}
+	defer stderr.Close()
+
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return errors.Context(err, "starting app")
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+
+	if err := a.follow(exited, false); err != nil {
+		return err
+	}
+
+	a.Pid = cmd.Process.Pid
+	a.pgid = cmd.Process.Pid
+	a.wait = func() { <-exited }
+	a.cmd = cmd
+	a.limit(cmd.Process.Pid)
+	return nil
+}
+
+// adoptApplication takes over a process group left running by a previous
+// zapd, following its spooled output from the current end
+func (a *adapter) adoptApplication(pid int) error {
+	if len(groupPids(pid)) == 0 {
+		return errors.Format("process group %d has exited", pid)
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		for len(groupPids(pid)) > 0 {
+			time.Sleep(500 * time.Millisecond)
+		}
+		close(exited)
+	}()
+
+	if err := a.follow(exited, true); err != nil {
+		return err
+	}
+
+	log.Println("[app]", a.Host, "adopted process group", pid, "on", a.address())
+	a.Command = a.ShellCommand
+	a.Pid = pid
+	a.pgid = pid
+	a.wait = func() { <-exited }
+	return nil
+}
+
+func (a *adapter) spoolPaths() (string, string) {
+	base := filepath.Join(a.SpoolDir, a.Host)
+	return base + ".stdout", base + ".stderr"
+}
+
+// follow reads the spooled output until the process has exited
+func (a *adapter) follow(exited <-chan struct{}, fromEnd bool) error {
+	stdoutPath, stderrPath := a.spoolPaths()
+
+	stdout, err := followFile(stdoutPath, fromEnd, a.LogMaxSize, exited)
+	if err != nil {
+		return err
+	}
+
+	stderr, err := followFile(stderrPath, fromEnd, a.LogMaxSize, exited)
+	if err != nil {
+		stdout.Close()
+		return err
+	}
+
+	a.stdout = stdout
+	a.stderr = stderr
+	return nil
+}
+
+// fileFollower reads a file as it's written, like tail -f, returning EOF once
+// exited is closed and everything has been read. The file is emptied once it
+// has all been read and has grown beyond maxSize
+type fileFollower struct {
+	*os.File
+	maxSize int64
+	exited  <-chan struct{}
+}
+
+func followFile(path string, fromEnd bool, maxSize int64, exited <-chan struct{}) (*fileFollower, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0644)
+	if err != nil {
+		return nil, errors.Context(err, "opening spool")
+	}
+	if fromEnd {
+		f.Seek(0, io.SeekEnd)
+	}
+	return &fileFollower{File: f, maxSize: maxSize, exited: exited}, nil
+}
+
+func (f *fileFollower) Read(p []byte) (int, error) {
+	for {
+		n, err := f.File.Read(p)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+		f.truncate()
+
+		select {
+		case <-f.exited:
+			n, err := f.File.Read(p)
+			if n == 0 {
+				f.File.Close()
+			}
+			return n, err
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// truncate empties the file once everything written to it has been read and
+// it has grown beyond maxSize. The process appends so it carries on writing
+// from the start, anything written between the last read and the truncate is
+// lost
+func (f *fileFollower) truncate() {
+	if f.maxSize <= 0 {
+		return
+	}
+	offset, err := f.File.Seek(0, io.SeekCurrent)
+	if err != nil || offset < f.maxSize {
+		return
+	}
+	if info, err := f.File.Stat(); err != nil || info.Size() != offset {
+		return
+	}
+	if err := os.Truncate(f.File.Name(), 0); err != nil {
+		return
+	}
+	f.File.Seek(0, io.SeekStart)
+}
+
+// Reap stops an orphaned process group left running by a previous zapd,
+// returning the pids that had to be killed
+func Reap(pgid int, timeout time.Duration) ([]int, error) {
+	if len(groupPids(pgid)) == 0 {
+		return nil, nil
+	}
+	return stopGroup(pgid, nil, timeout)
+}
+
+// Kill kills a process group straight away, for app processes still running
+// when zapd can't wait any longer
+func Kill(pgid int) error {
+	return killGroup(pgid)
+}
+
+// OwnedBy reports whether the process was started by zapd for the given app
+// host, guarding against the pid having been reused
+func OwnedBy(pid int, host string) bool {
+	return processHasEnv(pid, appEnvMarker+"="+host)
+}
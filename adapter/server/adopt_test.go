@@ -0,0 +1,99 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFollowFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zap-spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "moo.test.stdout")
+	if err := ioutil.WriteFile(path, []byte("before adoption\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	exited := make(chan struct{})
+	f, err := followFile(path, true, 0, exited)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		w.WriteString("first\n")
+		time.Sleep(200 * time.Millisecond)
+		w.WriteString("second\n")
+		w.Close()
+		close(exited)
+	}()
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "first\nsecond\n" {
+		t.Errorf("expected the lines written after following, got %q", data)
+	}
+}
+
+func TestFollowFileTruncates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zap-spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "moo.test.stdout")
+	w, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	exited := make(chan struct{})
+	f, err := followFile(path, false, 10, exited)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(expected string) {
+		p := make([]byte, 100)
+		n, err := f.Read(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(p[:n]) != expected {
+			t.Fatalf("expected %q, got %q", expected, p[:n])
+		}
+	}
+
+	w.WriteString("more than ten bytes\n")
+	read("more than ten bytes\n")
+
+	// the next read finds the end and empties the spool before waiting
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteString("after\n")
+	}()
+	read("after\n")
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len("after\n")) {
+		t.Errorf("expected the spool to be truncated, it's %d bytes", info.Size())
+	}
+	close(exited)
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/moomerman/zap/rproxy"
+)
+
+// backend is the port or socket of a web process along with its proxies and
+// the requests in flight to it, so the process can be drained before it's
+// stopped
+type backend struct {
+	Scheme string
+	Addr   string
+
+	mu      sync.Mutex
+	proxies map[string]*rproxy.ReverseProxy
+	active  int64
+}
+
+func newBackend(scheme, addr string) *backend {
+	return &backend{
+		Scheme:  scheme,
+		Addr:    addr,
+		proxies: make(map[string]*rproxy.ReverseProxy),
+	}
+}
+
+// getProxy returns the proxy for the given host, creating it on first use
+func (b *backend) getProxy(host string) (*rproxy.ReverseProxy, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.proxies[host] != nil {
+		return b.proxies[host], nil
+	}
+
+	url, err := url.Parse(addressURL(b.Scheme, b.Addr))
+	if err != nil {
+		return nil, err
+	}
+
+	var proxy *rproxy.ReverseProxy
+	if isSocket(b.Addr) {
+		proxy, err = rproxy.NewWithSocket(url, host, b.Addr)
+	} else {
+		proxy, err = rproxy.New(url, host)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b.proxies[host] = proxy
+
+	return proxy, nil
+}
+
+func (b *backend) release() {
+	atomic.AddInt64(&b.active, -1)
+}
+
+// drain waits up to timeout for the requests in flight to finish, returning
+// how many were still going
+func (b *backend) drain(timeout time.Duration) int64 {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(timeout)
+
+	for {
+		active := atomic.LoadInt64(&b.active)
+		if active == 0 {
+			return 0
+		}
+
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			return active
+		}
+	}
+}
+
+// acquireBackend returns the backend requests are currently sent to, counting
+// the request as in flight until it's released
+func (a *adapter) acquireBackend() *backend {
+	a.proxiesMu.Lock()
+	defer a.proxiesMu.Unlock()
+	atomic.AddInt64(&a.backend.active, 1)
+	return a.backend
+}
+
+// switchBackend sends new requests to the given backend and returns the
+// previous one
+func (a *adapter) switchBackend(b *backend) *backend {
+	a.proxiesMu.Lock()
+	defer a.proxiesMu.Unlock()
+	previous := a.backend
+	a.backend = b
+	return previous
+}
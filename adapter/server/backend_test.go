@@ -0,0 +1,36 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackendDrain(t *testing.T) {
+	a := &adapter{}
+	old := newBackend("http", "3000")
+	a.switchBackend(old)
+
+	b := a.acquireBackend()
+	if b != old {
+		t.Fatal("expected the current backend")
+	}
+
+	if previous := a.switchBackend(newBackend("http", "3001")); previous != old {
+		t.Fatal("expected the previous backend to be returned")
+	}
+	if b := a.acquireBackend(); b.Addr != "3001" {
+		t.Error("expected new requests to go to the new backend, got", b.Addr)
+	}
+
+	if active := old.drain(200 * time.Millisecond); active != 1 {
+		t.Error("expected 1 request still in flight, got", active)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		b.release()
+	}()
+	if active := old.drain(time.Second); active != 0 {
+		t.Error("expected the backend to drain, got", active)
+	}
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	zadapter "github.com/moomerman/zap/adapter"
+	"github.com/vektra/errors"
+)
+
+// buildProcess is the process name build output is logged under
+const buildProcess = "build"
+
+// Build runs the command in the app directory the same way the app command is
+// run, logging its output, and returns an error if it fails. The running app
+// is left untouched
+func (a *adapter) Build(command string) error {
+	a.Lock()
+	cmd := a.newCommand(command, "")
+	logFile := a.logFile
+	a.Unlock()
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	log.Println("[app]", a.Host, "building", command)
+	started := time.Now()
+
+	if err := cmd.Start(); err != nil {
+		return errors.Context(err, "starting build")
+	}
+
+	var wg sync.WaitGroup
+	read := func(stream string, r io.Reader) {
+		defer wg.Done()
+		br := bufio.NewReader(r)
+		for {
+			text, err := br.ReadString('\n')
+			if text != "" {
+				a.appendLog(logFile, cmd.Process.Pid, zadapter.LogLine{Time: time.Now(), Process: buildProcess, Stream: stream, Text: text})
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	wg.Add(2)
+	go read(zadapter.StreamStdout, stdout)
+	go read(zadapter.StreamStderr, stderr)
+	wg.Wait()
+
+	if err := cmd.Wait(); err != nil {
+		return errors.Context(err, "build failed")
+	}
+
+	log.Println("[app]", a.Host, "build finished in", time.Since(started).Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/vektra/errors"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cpuPeriod is the cgroup cpu.max period in microseconds
+const cpuPeriod = 100000
+
+var cgroupName = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+var (
+	delegatedMu   sync.Mutex
+	delegatedPath string
+)
+
+// cgroup is a cgroup v2 sub-group of the zapd cgroup that enforces the
+// memory and CPU limits of an app, its methods do nothing on a nil cgroup
+type cgroup struct {
+	Path     string
+	oomKills int64
+}
+
+// newCgroup creates a sub-group for the app with the given limits, it needs
+// cgroup v2 and zapd running in a cgroup that has been delegated to the user
+func newCgroup(name string, limits Limits) (*cgroup, error) {
+	if limits.MaxMemory == 0 && limits.CPUQuota == 0 {
+		return nil, nil
+	}
+
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return nil, errors.New("cgroup v2 is not available")
+	}
+
+	parent, err := delegatedCgroup()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &cgroup{Path: filepath.Join(parent, "zap-"+cgroupName.ReplaceAllString(name, "_"))}
+	if err := os.Mkdir(c.Path, 0755); err != nil && !os.IsExist(err) {
+		return nil, errors.Context(err, "creating cgroup")
+	}
+
+	if limits.MaxMemory > 0 {
+		if err := c.write("memory.max", strconv.FormatInt(limits.MaxMemory, 10)); err != nil {
+			c.Remove()
+			return nil, err
+		}
+	}
+	if limits.CPUQuota > 0 {
+		if err := c.write("cpu.max", fmt.Sprintf("%d %d", int64(limits.CPUQuota*cpuPeriod), cpuPeriod)); err != nil {
+			c.Remove()
+			return nil, err
+		}
+	}
+
+	c.oomKills = c.readOOMKills()
+	return c, nil
+}
+
+// delegatedCgroup returns the cgroup zapd was started in with the memory and
+// cpu controllers enabled for its sub-groups. cgroup v2 only allows that for a
+// group with no processes of its own, so zapd moves itself into a zapd
+// sub-group first. Nothing is changed unless the group has been delegated to
+// zapd and zapd is the only process in it, a login session or a service that
+// systemd manages is left alone
+func delegatedCgroup() (string, error) {
+	delegatedMu.Lock()
+	defer delegatedMu.Unlock()
+
+	if delegatedPath != "" {
+		return delegatedPath, nil
+	}
+
+	self, err := ioutil.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", errors.Context(err, "reading own cgroup")
+	}
+
+	parent := ""
+	for _, line := range strings.Split(string(self), "\n") {
+		if strings.HasPrefix(line, "0::") {
+			parent = filepath.Join(cgroupRoot, strings.TrimPrefix(line, "0::"))
+		}
+	}
+	if parent == "" || parent == cgroupRoot {
+		return "", errors.New("zapd is not in a cgroup v2 sub-group")
+	}
+
+	if !isDelegated(parent) {
+		return "", errors.Format("cgroup %s has not been delegated to zapd", parent)
+	}
+
+	procs, err := ioutil.ReadFile(filepath.Join(parent, "cgroup.procs"))
+	if err != nil {
+		return "", errors.Context(err, "reading cgroup processes")
+	}
+	if pids := strings.Fields(string(procs)); len(pids) != 1 || pids[0] != strconv.Itoa(os.Getpid()) {
+		return "", errors.Format("cgroup %s has processes other than zapd", parent)
+	}
+
+	leaf := filepath.Join(parent, "zapd")
+	if err := os.Mkdir(leaf, 0755); err != nil && !os.IsExist(err) {
+		return "", errors.Context(err, "creating cgroup")
+	}
+	if err := ioutil.WriteFile(filepath.Join(leaf, "cgroup.procs"), []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		os.Remove(leaf)
+		return "", errors.Context(err, "moving zapd into its own cgroup")
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte("+memory +cpu"), 0644); err != nil {
+		return "", errors.Context(err, "enabling cgroup controllers")
+	}
+
+	delegatedPath = parent
+	return parent, nil
+}
+
+// isDelegated reports whether the cgroup has been handed to zapd to manage.
+// systemd gives the user the group and its control files for a user service
+// with Delegate=yes, and marks the group with an xattr for a system service
+// where everything is owned by root
+func isDelegated(path string) bool {
+	uid := os.Geteuid()
+	if uid == 0 {
+		for _, attr := range []string{"trusted.delegate", "user.delegate"} {
+			if n, err := syscall.Getxattr(path, attr, nil); err == nil && n > 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, file := range []string{"", "cgroup.procs", "cgroup.subtree_control"} {
+		info, err := os.Stat(filepath.Join(path, file))
+		if err != nil {
+			return false
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok || int(stat.Uid) != uid {
+			return false
+		}
+	}
+	return true
+}
+
+// Add moves the process into the cgroup, its children inherit it
+func (c *cgroup) Add(pid int) error {
+	if c == nil {
+		return nil
+	}
+	return c.write("cgroup.procs", strconv.Itoa(pid))
+}
+
+// OOMKilled reports whether a process in the cgroup has been killed for
+// going over memory.max since the cgroup was created
+func (c *cgroup) OOMKilled() bool {
+	if c == nil {
+		return false
+	}
+	return c.readOOMKills() > c.oomKills
+}
+
+// Remove removes the cgroup once all of its processes have exited
+func (c *cgroup) Remove() error {
+	if c == nil {
+		return nil
+	}
+	return os.Remove(c.Path)
+}
+
+func (c *cgroup) write(file, value string) error {
+	if err := ioutil.WriteFile(filepath.Join(c.Path, file), []byte(value), 0644); err != nil {
+		return errors.Context(err, "writing "+file)
+	}
+	return nil
+}
+
+func (c *cgroup) readOOMKills() int64 {
+	f, err := os.Open(filepath.Join(c.Path, "memory.events"))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			n, _ := strconv.ParseInt(fields[1], 10, 64)
+			return n
+		}
+	}
+	return 0
+}
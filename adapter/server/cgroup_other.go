@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package server
+
+// cgroup is only supported on Linux, its methods do nothing elsewhere
+type cgroup struct{}
+
+func newCgroup(name string, limits Limits) (*cgroup, error) { return nil, nil }
+
+func (c *cgroup) Add(pid int) error { return nil }
+
+func (c *cgroup) OOMKilled() bool { return false }
+
+func (c *cgroup) Remove() error { return nil }
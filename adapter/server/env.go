@@ -1,29 +1,323 @@
 package server
 
 import (
-	"bufio"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vektra/errors"
 )
 
-func readEnvFile(dir string) ([]string, error) {
-	file := dir + "/.env"
+// envFiles are read from the app directory in order, later files override
+// the values in earlier ones
+var envFiles = []string{".env", ".env.development", ".env.local"}
+
+var envKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// envVar is a single variable from an env file
+type envVar struct {
+	Key   string
+	Value string
+}
+
+// readEnv reads the layered env files in dir and then the extra variables
+// from the app config, returning KEY=value pairs. A file that can't be parsed
+// is skipped and its error returned alongside the variables from the rest
+func readEnv(dir string, extra map[string]string) ([]string, []error) {
+	values := map[string]string{}
+	order := []string{}
+	errs := []error{}
+
+	set := func(key, value string) {
+		if _, ok := values[key]; !ok {
+			order = append(order, key)
+		}
+		values[key] = value
+	}
+
+	lookup := func(key string) (string, bool) {
+		if value, ok := values[key]; ok {
+			return value, true
+		}
+		return os.LookupEnv(key)
+	}
+
+	for _, name := range envFiles {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, errors.Context(err, "reading "+name))
+			continue
+		}
+
+		vars, err := parseEnv(name, string(data), lookup)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, v := range vars {
+			set(v.Key, v.Value)
+		}
+	}
+
+	keys := []string{}
+	for key := range extra {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !envKey.MatchString(key) {
+			errs = append(errs, errors.Format("env: invalid name %q", key))
+			continue
+		}
+		set(key, expandEnv(extra[key], lookup))
+	}
+
 	env := []string{}
+	for _, key := range order {
+		env = append(env, key+"="+values[key])
+	}
+	return env, errs
+}
+
+// parseEnv parses the contents of a dotenv file. Values can be unquoted,
+// single quoted (taken literally) or double quoted (with escapes), quoted
+// values can span lines and ${VAR} is expanded in unquoted and double quoted
+// values using lookup and the variables defined earlier in the file
+func parseEnv(name, data string, lookup func(string) (string, bool)) ([]envVar, error) {
+	vars := []envVar{}
+	defined := map[string]string{}
+
+	resolve := func(key string) (string, bool) {
+		if value, ok := defined[key]; ok {
+			return value, true
+		}
+		return lookup(key)
+	}
+
+	p := &envParser{name: name, data: data, line: 1}
+	for {
+		p.skipBlank()
+		if p.done() {
+			return vars, nil
+		}
+
+		if p.peek() == '#' {
+			p.skipLine()
+			continue
+		}
+
+		key := p.readKey()
+		if key == "export" && (p.peek() == ' ' || p.peek() == '\t') {
+			p.skipSpaces()
+			key = p.readKey()
+		}
+		if !envKey.MatchString(key) {
+			return nil, p.errorf("invalid name %q", key)
+		}
+
+		p.skipSpaces()
+		if p.done() || p.peek() != '=' {
+			return nil, p.errorf("expected = after %s", key)
+		}
+		p.pos++
+		p.skipSpaces()
+
+		value, err := p.readValue(resolve)
+		if err != nil {
+			return nil, err
+		}
+
+		defined[key] = value
+		vars = append(vars, envVar{Key: key, Value: value})
+	}
+}
+
+// envParser holds the position in a dotenv file being parsed
+type envParser struct {
+	name string
+	data string
+	pos  int
+	line int
+}
 
-	if _, err := os.Stat(file); os.IsNotExist(err) {
-		return env, nil
+func (p *envParser) errorf(format string, args ...interface{}) error {
+	return errors.Format("%s:%d: "+format, append([]interface{}{p.name, p.line}, args...)...)
+}
+
+func (p *envParser) done() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *envParser) peek() byte {
+	return p.data[p.pos]
+}
+
+func (p *envParser) next() byte {
+	c := p.data[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+func (p *envParser) skipBlank() {
+	for !p.done() && strings.IndexByte(" \t\r\n", p.peek()) >= 0 {
+		p.next()
+	}
+}
+
+func (p *envParser) skipSpaces() {
+	for !p.done() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+func (p *envParser) skipLine() {
+	for !p.done() && p.next() != '\n' {
+	}
+}
+
+func (p *envParser) readKey() string {
+	start := p.pos
+	for !p.done() && strings.IndexByte(" \t\r\n=#", p.peek()) < 0 {
+		p.pos++
+	}
+	return p.data[start:p.pos]
+}
+
+func (p *envParser) readValue(lookup func(string) (string, bool)) (string, error) {
+	if p.done() {
+		return "", nil
+	}
+
+	switch p.peek() {
+	case '\'':
+		p.next()
+		start := p.pos
+		for !p.done() && p.peek() != '\'' {
+			p.next()
+		}
+		if p.done() {
+			return "", p.errorf("unterminated single quoted value")
+		}
+		value := p.data[start:p.pos]
+		p.next()
+		return value, p.endOfValue()
+
+	case '"':
+		p.next()
+		// the text between escapes is expanded as it's read so an escaped
+		// character is always literal, eg. "\\$HOME" is \ and the home dir
+		var b, text strings.Builder
+		expand := func() {
+			b.WriteString(expandEnv(text.String(), lookup))
+			text.Reset()
+		}
+		for !p.done() && p.peek() != '"' {
+			c := p.next()
+			if c == '\\' && !p.done() {
+				expand()
+				switch e := p.next(); e {
+				case 'n':
+					b.WriteByte('\n')
+				case 'r':
+					b.WriteByte('\r')
+				case 't':
+					b.WriteByte('\t')
+				default:
+					b.WriteByte(e)
+				}
+				continue
+			}
+			text.WriteByte(c)
+		}
+		if p.done() {
+			return "", p.errorf("unterminated double quoted value")
+		}
+		p.next()
+		expand()
+		return b.String(), p.endOfValue()
 	}
 
-	inFile, err := os.Open(file)
-	if err != nil {
-		return env, err
+	start := p.pos
+	for !p.done() && p.peek() != '\n' {
+		// a comment has to be preceded by whitespace, eg. FOO=bar #baz
+		if p.peek() == '#' && p.pos > start && (p.data[p.pos-1] == ' ' || p.data[p.pos-1] == '\t') {
+			break
+		}
+		p.pos++
 	}
-	defer inFile.Close()
-	scanner := bufio.NewScanner(inFile)
-	scanner.Split(bufio.ScanLines)
+	value := strings.TrimSpace(p.data[start:p.pos])
+	p.skipLine()
+	return expandEnv(value, lookup), nil
+}
+
+// endOfValue checks that nothing but a comment follows a quoted value
+func (p *envParser) endOfValue() error {
+	p.skipSpaces()
+	if p.done() {
+		return nil
+	}
+	switch p.peek() {
+	case '\r', '\n', '#':
+		p.skipLine()
+		return nil
+	}
+	return p.errorf("unexpected %q after quoted value", p.peek())
+}
+
+// expandEnv replaces ${VAR} and $VAR with their values, \$ is a literal $
+func expandEnv(value string, lookup func(string) (string, bool)) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c == '\\' && i+1 < len(value) && value[i+1] == '$' {
+			b.WriteByte('$')
+			i++
+			continue
+		}
+		if c != '$' || i+1 == len(value) {
+			b.WriteByte(c)
+			continue
+		}
 
-	for scanner.Scan() {
-		env = append(env, scanner.Text())
+		var key string
+		if value[i+1] == '{' {
+			end := strings.IndexByte(value[i:], '}')
+			if end < 0 {
+				b.WriteByte(c)
+				continue
+			}
+			key = value[i+2 : i+end]
+			i += end
+		} else {
+			j := i + 1
+			for j < len(value) && (value[j] == '_' || isAlphaNum(value[j])) {
+				j++
+			}
+			if j == i+1 {
+				b.WriteByte(c)
+				continue
+			}
+			key = value[i+1 : j]
+			i = j - 1
+		}
+
+		if v, ok := lookup(key); ok {
+			b.WriteString(v)
+		}
 	}
+	return b.String()
+}
 
-	return env, nil
+func isAlphaNum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
 }
@@ -1,14 +1,48 @@
 package server
 
-import "testing"
+import (
+	"os"
+	"strings"
+	"testing"
+)
 
 func TestEnv(t *testing.T) {
-	env, err := readEnvFile("../rails/test/rails5.1")
-	if err != nil {
-		t.Error(err)
+	os.Setenv("PORT_NUMBER", "4000")
+	defer os.Unsetenv("PORT_NUMBER")
+
+	env, errs := readEnv("test/app", map[string]string{"FROM_CONFIG": "$MOO-${HOST}"})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	expected := []string{
+		"MOO=bar",
+		"HOST=localhost",
+		"URL=http://localhost:4000/app",
+		"LITERAL=${HOST} stays",
+		"ESCAPED=line1\nline2 \"quoted\" $HOST",
+		"BACKSLASH=a\\localhost",
+		"MULTILINE=first\nsecond",
+		"HASH=a#b",
+		"EMPTY=",
+		"FROM_CONFIG=bar-localhost",
+	}
+	if strings.Join(env, "|") != strings.Join(expected, "|") {
+		t.Errorf("unexpected env\n%q\nexpected\n%q", env, expected)
+	}
+}
+
+func TestEnvErrors(t *testing.T) {
+	lookup := func(string) (string, bool) { return "", false }
+
+	for _, data := range []string{"FOO bar", "FOO=\"unterminated\nBAR=1", "FOO='a' b", "1FOO=bar"} {
+		if _, err := parseEnv(".env", data, lookup); err == nil {
+			t.Errorf("expected an error parsing %q", data)
+		}
 	}
 
-	if env[0] != "MOO=foo" {
-		t.Error("expected env var to match")
+	_, err := parseEnv(".env", "A=1\n\nB='x'y", lookup)
+	if err == nil || !strings.HasPrefix(err.Error(), ".env:3:") {
+		t.Error("expected the line number in the error, got", err)
 	}
 }
@@ -0,0 +1,21 @@
+package server
+
+// Limits are the resource limits applied to the app processes
+type Limits struct {
+	// MaxMemory is the memory limit in bytes, enforced with the cgroup
+	// memory.max or, without a cgroup, by stopping the app if the PSS of its
+	// process tree goes over
+	MaxMemory int64
+	// MaxOpenFiles is the RLIMIT_NOFILE for each process
+	MaxOpenFiles int
+	// Nice is the scheduling priority of the processes
+	Nice int
+	// CPUQuota is the number of CPUs the app can use, only enforced with
+	// cgroups, eg. 0.5 for half of one CPU
+	CPUQuota float64
+}
+
+// Empty reports whether no limits are set
+func (l Limits) Empty() bool {
+	return l == Limits{}
+}
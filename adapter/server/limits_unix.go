@@ -0,0 +1,34 @@
+//go:build !windows
+// +build !windows
+
+package server
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// withLimits wraps the command in a shell that sets the rlimits and nice
+// value before running it, exec.Cmd has no way to set them on the child
+// directly. nice is relative to zapd's own priority
+func withLimits(cmd *exec.Cmd, limits Limits) *exec.Cmd {
+	steps := []string{}
+	if limits.MaxOpenFiles > 0 {
+		steps = append(steps, fmt.Sprintf("ulimit -n %d", limits.MaxOpenFiles))
+	}
+	run := `exec "$@"`
+	if limits.Nice != 0 {
+		run = fmt.Sprintf(`exec nice -n %d "$@"`, limits.Nice)
+	}
+	if len(steps) == 0 && limits.Nice == 0 {
+		return cmd
+	}
+
+	script := strings.Join(append(steps, run), " && ")
+	args := append([]string{"-c", script, "zap-limits", cmd.Path}, cmd.Args[1:]...)
+
+	wrapped := exec.Command(defaultShell, args...)
+	wrapped.Env = cmd.Env
+	return wrapped
+}
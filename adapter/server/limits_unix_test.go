@@ -0,0 +1,27 @@
+//go:build !windows
+// +build !windows
+
+package server
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWithLimitsNice(t *testing.T) {
+	before, err := exec.Command("nice").Output()
+	if err != nil {
+		t.Skip("nice is not available", err)
+	}
+	base, _ := strconv.Atoi(strings.TrimSpace(string(before)))
+
+	out, err := withLimits(exec.Command("nice"), Limits{Nice: 5, MaxOpenFiles: 256}).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := strconv.Atoi(strings.TrimSpace(string(out))); n != base+5 && n != 19 {
+		t.Errorf("expected the command to run with nice %d, got %s", base+5, out)
+	}
+}
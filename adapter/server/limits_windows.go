@@ -0,0 +1,6 @@
+package server
+
+import "os/exec"
+
+// rlimits and nice aren't supported on windows
+func withLimits(cmd *exec.Cmd, limits Limits) *exec.Cmd { return cmd }
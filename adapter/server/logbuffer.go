@@ -0,0 +1,62 @@
+package server
+
+import (
+	"io"
+	"sync"
+
+	zadapter "github.com/moomerman/zap/adapter"
+)
+
+// defaultLogBufferSize is the number of lines kept in memory
+const defaultLogBufferSize = 1024
+
+// logBuffer is a ring buffer of the most recent log lines
+type logBuffer struct {
+	Size int
+
+	mu    sync.Mutex
+	cur   int
+	lines []zadapter.LogLine
+}
+
+// Append adds a line, overwriting the oldest once the buffer is full
+func (b *logBuffer) Append(line zadapter.LogLine) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.Size == 0 {
+		b.Size = defaultLogBufferSize
+	}
+
+	if len(b.lines) < b.Size {
+		b.lines = append(b.lines, line)
+		return
+	}
+
+	b.lines[b.cur] = line
+	b.cur = (b.cur + 1) % b.Size
+}
+
+// Lines returns a copy of the buffered lines, oldest first
+func (b *logBuffer) Lines() []zadapter.LogLine {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	lines := make([]zadapter.LogLine, 0, len(b.lines))
+	lines = append(lines, b.lines[b.cur:]...)
+	lines = append(lines, b.lines[:b.cur]...)
+	return lines
+}
+
+// WriteTo writes the text of every buffered line to the writer
+func (b *logBuffer) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for _, line := range b.Lines() {
+		n, err := io.WriteString(w, line.Text)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
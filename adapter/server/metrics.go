@@ -0,0 +1,101 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	zadapter "github.com/moomerman/zap/adapter"
+	"github.com/vektra/errors"
+)
+
+// metricsInterval is how often the resources used by the app are sampled
+// and metricsHistory is how many samples are kept
+const (
+	metricsInterval = 5 * time.Second
+	metricsHistory  = 60
+)
+
+// metrics is a short history of resource samples for the app process tree
+type metrics struct {
+	mu      sync.Mutex
+	samples []zadapter.ProcessMetrics
+}
+
+// Add records a sample, working out the CPU usage since the previous one
+func (m *metrics) Add(sample zadapter.ProcessMetrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if n := len(m.samples); n > 0 {
+		prev := m.samples[n-1]
+		if elapsed := sample.Time.Sub(prev.Time); elapsed > 0 && sample.CPUTime >= prev.CPUTime {
+			sample.CPUPercent = float64(sample.CPUTime-prev.CPUTime) / float64(elapsed) * 100
+		}
+	}
+
+	m.samples = append(m.samples, sample)
+	if len(m.samples) > metricsHistory {
+		m.samples = m.samples[len(m.samples)-metricsHistory:]
+	}
+}
+
+// Samples returns the samples, oldest first
+func (m *metrics) Samples() []zadapter.ProcessMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]zadapter.ProcessMetrics{}, m.samples...)
+}
+
+// MarshalJSON locks the history so it can be read while sampling
+func (m *metrics) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Samples())
+}
+
+// sampleMetrics samples the resources used by the web and worker processes
+// and their children until cancel is closed
+func (a *adapter) sampleMetrics(cancel chan struct{}, pid int) {
+	ticker := time.NewTicker(metricsInterval)
+	defer ticker.Stop()
+
+	for {
+		// Replace and worker restarts change these while the app runs
+		a.Lock()
+		workers := append([]*worker{}, a.Workers...)
+		resources := a.Resources
+		cgroup := a.cgroup
+		a.Unlock()
+
+		roots := []int{pid}
+		for _, w := range workers {
+			if status := w.status(); status.Pid != 0 {
+				roots = append(roots, status.Pid)
+			}
+		}
+
+		sample, err := sampleProcessTree(roots)
+		if err == errMetricsUnsupported {
+			return
+		}
+		if err != nil {
+			log.Println("[app]", a.Host, "error sampling metrics", err)
+		} else {
+			resources.Add(sample)
+			// the cgroup memory.max enforces the limit when there is one
+			if cgroup == nil && a.Limits.MaxMemory > 0 && sample.PSS > a.Limits.MaxMemory {
+				reason := fmt.Sprintf("killed for exceeding max_memory (%d bytes used, limit %d)", sample.PSS, a.Limits.MaxMemory)
+				log.Println("[app]", a.Host, reason)
+				a.Stop(errors.New(reason))
+				return
+			}
+		}
+
+		select {
+		case <-cancel:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	zadapter "github.com/moomerman/zap/adapter"
+)
+
+// clockTicks is the kernel USER_HZ that CPU times in /proc are counted in,
+// it's 100 on every architecture Linux supports
+const clockTicks = 100
+
+var errMetricsUnsupported error
+
+// procStat is the part of /proc/<pid>/stat used for metrics
+type procStat struct {
+	pid     int
+	ppid    int
+	cpu     int64
+	threads int
+	rss     int64
+}
+
+// sampleProcessTree adds up the resources used by the given processes and
+// all of their descendants
+func sampleProcessTree(roots []int) (zadapter.ProcessMetrics, error) {
+	sample := zadapter.ProcessMetrics{Time: time.Now()}
+
+	entries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return sample, err
+	}
+
+	stats := map[int]procStat{}
+	children := map[int][]int{}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		stat, ok := readProcStat(pid)
+		if !ok {
+			continue
+		}
+		stats[pid] = stat
+		children[stat.ppid] = append(children[stat.ppid], pid)
+	}
+
+	seen := map[int]bool{}
+	queue := append([]int{}, roots...)
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+
+		stat, ok := stats[pid]
+		if !ok || seen[pid] {
+			continue
+		}
+		seen[pid] = true
+		queue = append(queue, children[pid]...)
+
+		sample.Processes++
+		sample.CPUTime += time.Duration(stat.cpu) * time.Second / clockTicks
+		sample.Threads += stat.threads
+		sample.RSS += stat.rss * int64(os.Getpagesize())
+		sample.PSS += readPSS(pid, stat.rss*int64(os.Getpagesize()))
+
+		if fds, err := ioutil.ReadDir("/proc/" + strconv.Itoa(pid) + "/fd"); err == nil {
+			sample.FDs += len(fds)
+		}
+	}
+
+	return sample, nil
+}
+
+// readPSS returns the proportional set size of the process from
+// smaps_rollup, falling back to its RSS on kernels older than 4.14
+func readPSS(pid int, rss int64) int64 {
+	data, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/smaps_rollup")
+	if err != nil {
+		return rss
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[0] == "Pss:" {
+			kb, _ := strconv.ParseInt(fields[1], 10, 64)
+			return kb * 1024
+		}
+	}
+	return rss
+}
+
+func readProcStat(pid int) (procStat, bool) {
+	data, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return procStat{}, false
+	}
+
+	// the command name is in parens and may contain spaces so the remaining
+	// fields start after the last closing paren, the state is field 3
+	stat := string(data)
+	fields := strings.Fields(stat[strings.LastIndexByte(stat, ')')+1:])
+	if len(fields) < 22 || fields[0] == "Z" {
+		return procStat{}, false
+	}
+
+	field := func(n int) int64 {
+		v, _ := strconv.ParseInt(fields[n-3], 10, 64)
+		return v
+	}
+
+	return procStat{
+		pid:     pid,
+		ppid:    int(field(4)),
+		cpu:     field(14) + field(15),
+		threads: int(field(20)),
+		rss:     field(24),
+	}, true
+}
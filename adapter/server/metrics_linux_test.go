@@ -0,0 +1,29 @@
+package server
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSampleProcessTree(t *testing.T) {
+	sample, err := sampleProcessTree([]int{os.Getpid()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sample.Processes < 1 || sample.RSS <= 0 || sample.PSS <= 0 || sample.Threads < 1 || sample.FDs < 1 {
+		t.Error("expected metrics for the test process, got", sample)
+	}
+
+	m := &metrics{}
+	m.Add(sample)
+	next := sample
+	next.Time = sample.Time.Add(time.Second)
+	next.CPUTime = sample.CPUTime + 500*time.Millisecond
+	m.Add(next)
+
+	if samples := m.Samples(); len(samples) != 2 || samples[1].CPUPercent != 50 {
+		t.Error("expected 50% CPU, got", samples)
+	}
+}
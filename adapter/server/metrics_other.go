@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package server
+
+import (
+	zadapter "github.com/moomerman/zap/adapter"
+	"github.com/vektra/errors"
+)
+
+var errMetricsUnsupported = errors.New("process metrics are only supported on linux")
+
+// sampleProcessTree isn't supported outside of Linux
+func sampleProcessTree(roots []int) (zadapter.ProcessMetrics, error) {
+	return zadapter.ProcessMetrics{}, errMetricsUnsupported
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"hash/fnv"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/vektra/errors"
+)
+
+// ports is the registry of the ports given to apps, shared by every adapter
+// so apps starting at the same time are never given the same port
+var ports = &portRegistry{
+	inUse: map[int]string{},
+	last:  map[string]int{},
+}
+
+// portRegistry hands out ports from a range, remembering the last port each
+// app host was given so it can have the same one again after a restart
+type portRegistry struct {
+	sync.Mutex
+	From  int
+	To    int
+	inUse map[int]string
+	last  map[string]int
+}
+
+// SetPortRange sets the range app ports are allocated from, when it's empty
+// any free port is used
+func SetPortRange(from, to int) error {
+	if from < 0 || to > 65535 || from > to {
+		return errors.Format("invalid port range %d-%d", from, to)
+	}
+
+	ports.Lock()
+	defer ports.Unlock()
+	ports.From = from
+	ports.To = to
+	return nil
+}
+
+// portReservation is a port bound by zapd on behalf of an app, the listener
+// is held open until the app has been started so nothing else can take the
+// port, or handed to the app with socket activation
+type portReservation struct {
+	Port     string
+	listener *net.TCPListener
+	file     *os.File
+}
+
+// Close closes zapd's copy of the socket, the port stays assigned to the app
+// until it is released
+func (r *portReservation) Close() {
+	if r == nil {
+		return
+	}
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+	if r.listener != nil {
+		r.listener.Close()
+		r.listener = nil
+	}
+}
+
+// reservePort binds a port for the app host, preferring the port it was last
+// given, then trying the range starting from a point picked by the host
+func (p *portRegistry) reservePort(host string) (*portReservation, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	candidates := []int{}
+	if port, ok := p.last[host]; ok {
+		candidates = append(candidates, port)
+	}
+
+	if p.To > 0 {
+		size := p.To - p.From + 1
+		h := fnv.New32a()
+		h.Write([]byte(host))
+		start := int(h.Sum32() % uint32(size))
+		for i := 0; i < size; i++ {
+			candidates = append(candidates, p.From+(start+i)%size)
+		}
+	} else {
+		// any free port picked by the OS
+		candidates = append(candidates, 0)
+	}
+
+	for _, port := range candidates {
+		if port != 0 && p.inUse[port] != "" {
+			continue
+		}
+
+		l, err := net.ListenTCP("tcp", &net.TCPAddr{Port: port})
+		if err != nil {
+			continue
+		}
+
+		port = l.Addr().(*net.TCPAddr).Port
+		if p.inUse[port] != "" {
+			l.Close()
+			continue
+		}
+
+		p.inUse[port] = host
+		p.last[host] = port
+		return &portReservation{Port: strconv.Itoa(port), listener: l}, nil
+	}
+
+	if p.To > 0 {
+		return nil, errors.Format("no free ports in %d-%d", p.From, p.To)
+	}
+	return nil, errors.New("no free ports")
+}
+
+// claimPort records a port already in use by the app host, eg. an adopted
+// process
+func (p *portRegistry) claimPort(host, port string) {
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	p.inUse[n] = host
+	p.last[host] = n
+}
+
+// releasePort frees a port the app host has stopped using, it's still
+// preferred the next time the app starts
+func (p *portRegistry) releasePort(host, port string) {
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	if p.inUse[n] == host {
+		delete(p.inUse, n)
+	}
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"net"
+	"testing"
+)
+
+func TestReservePort(t *testing.T) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	p := &portRegistry{From: from, To: from + 1, inUse: map[int]string{}, last: map[string]int{}}
+
+	first, err := p.reservePort("one.dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Close()
+
+	second, err := p.reservePort("two.dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.Close()
+
+	if first.Port == second.Port {
+		t.Fatal("expected different ports while both are in use, got", first.Port)
+	}
+
+	if _, err := p.reservePort("three.dev"); err == nil {
+		t.Error("expected no free ports in the range")
+	}
+
+	p.releasePort("one.dev", first.Port)
+	again, err := p.reservePort("one.dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again.Close()
+	if again.Port != first.Port {
+		t.Error("expected the last port", first.Port, "got", again.Port)
+	}
+
+	// only the owner can release a port
+	p.releasePort("two.dev", first.Port)
+	if p.inUse[from] == "" || p.inUse[from+1] == "" {
+		t.Error("expected both ports to still be in use", p.inUse)
+	}
+}
@@ -0,0 +1,36 @@
+//go:build !windows
+// +build !windows
+
+package server
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/vektra/errors"
+)
+
+// withListener hands the reserved port's socket to the command as fd 3,
+// setting LISTEN_FDS the way systemd socket activation does. LISTEN_PID has
+// to be the pid of the process using the socket so it's set by a shell that
+// then execs the command
+func withListener(cmd *exec.Cmd, r *portReservation) (*exec.Cmd, error) {
+	if r == nil || r.listener == nil {
+		return nil, errors.New("no socket to pass to the app")
+	}
+
+	file, err := r.listener.File()
+	if err != nil {
+		return nil, errors.Context(err, "passing socket")
+	}
+	r.file = file
+
+	args := append([]string{"-c", `export LISTEN_PID=$$ && exec "$@"`, "zap-listen", cmd.Path}, cmd.Args[1:]...)
+
+	wrapped := exec.Command(defaultShell, args...)
+	wrapped.Env = append(cmd.Env, "LISTEN_FDS=1", "LISTEN_FDNAMES="+webProcess)
+	wrapped.Dir = cmd.Dir
+	wrapped.SysProcAttr = cmd.SysProcAttr
+	wrapped.ExtraFiles = []*os.File{file}
+	return wrapped, nil
+}
@@ -0,0 +1,12 @@
+package server
+
+import (
+	"os/exec"
+
+	"github.com/vektra/errors"
+)
+
+// sockets can't be passed to child processes on windows
+func withListener(cmd *exec.Cmd, r *portReservation) (*exec.Cmd, error) {
+	return nil, errors.New("socket activation isn't supported on windows")
+}
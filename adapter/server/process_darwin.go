@@ -0,0 +1,40 @@
+package server
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// groupPids returns the processes in the process group
+func groupPids(pgid int) []int {
+	pids := []int{}
+
+	out, err := exec.Command("pgrep", "-g", strconv.Itoa(pgid)).Output()
+	if err != nil {
+		return pids
+	}
+
+	for _, field := range strings.Fields(string(out)) {
+		if pid, err := strconv.Atoi(field); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+
+	return pids
+}
+
+// processHasEnv reports whether the environment of the process contains the
+// given KEY=value pair, ps shows the environment after the command
+func processHasEnv(pid int, pair string) bool {
+	out, err := exec.Command("ps", "-E", "-ww", "-o", "command=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return false
+	}
+	for _, field := range strings.Fields(string(out)) {
+		if field == pair {
+			return true
+		}
+	}
+	return false
+}
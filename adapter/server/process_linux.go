@@ -0,0 +1,57 @@
+package server
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// groupPids returns the live (non-zombie) processes in the process group
+func groupPids(pgid int) []int {
+	pids := []int{}
+
+	entries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return pids
+	}
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		stat, err := ioutil.ReadFile("/proc/" + entry.Name() + "/stat")
+		if err != nil {
+			continue
+		}
+
+		// the command name is in parens and may contain spaces so the
+		// remaining fields start after the last closing paren
+		fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+		if len(fields) < 3 || fields[0] == "Z" {
+			continue
+		}
+
+		if pgrp, _ := strconv.Atoi(fields[2]); pgrp == pgid {
+			pids = append(pids, pid)
+		}
+	}
+
+	return pids
+}
+
+// processHasEnv reports whether the environment of the process contains the
+// given KEY=value pair
+func processHasEnv(pid int, pair string) bool {
+	environ, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/environ")
+	if err != nil {
+		return false
+	}
+	for _, kv := range strings.Split(string(environ), "\x00") {
+		if kv == pair {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// openPty opens a new pseudo terminal, returning the master and the slave
+func openPty() (*os.File, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	unlock := 0
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+		master.Close()
+		return nil, nil, errno
+	}
+
+	var n uint32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); errno != 0 {
+		master.Close()
+		return nil, nil, errno
+	}
+
+	slave, err := os.OpenFile("/dev/pts/"+strconv.Itoa(int(n)), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	return master, slave, nil
+}
+
+// TestLoginShellUnderTerminal runs the app command the way zapd does from a
+// process that has a terminal, as when zapd is run from a shell
+func TestLoginShellUnderTerminal(t *testing.T) {
+	if os.Getenv("ZAP_TEST_PTY") == "1" {
+		a := &adapter{Dir: os.TempDir()}
+		cmd := a.newCommand("exec echo started", "")
+		out, err := cmd.Output()
+		if err != nil {
+			os.Exit(1)
+		}
+		os.Stdout.Write(out)
+		os.Exit(0)
+	}
+
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+
+	master, slave, err := openPty()
+	if err != nil {
+		t.Skip("no pty available", err)
+	}
+	defer master.Close()
+
+	// the test binary runs again as a session leader with the pty as its
+	// controlling terminal
+	cmd := exec.Command(os.Args[0], "-test.run=TestLoginShellUnderTerminal")
+	cmd.Env = append(os.Environ(), "ZAP_TEST_PTY=1", "SHELL=bash")
+	cmd.Stdin = slave
+	cmd.Stdout = slave
+	cmd.Stderr = slave
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	slave.Close()
+
+	output := make(chan []byte)
+	go func() {
+		var buf bytes.Buffer
+		b := make([]byte, 1024)
+		for {
+			n, err := master.Read(b)
+			buf.Write(b[:n])
+			if err != nil {
+				break
+			}
+		}
+		output <- buf.Bytes()
+	}()
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	select {
+	case err := <-exited:
+		if err != nil {
+			t.Error("app command failed under a terminal", err)
+		}
+	case <-time.After(10 * time.Second):
+		cmd.Process.Kill()
+		t.Fatal("app command stopped under a terminal")
+	}
+
+	if out := <-output; !bytes.Contains(out, []byte("started")) {
+		t.Errorf("expected the command output, got %q", out)
+	}
+}
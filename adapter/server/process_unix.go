@@ -0,0 +1,36 @@
+//go:build !windows
+// +build !windows
+
+package server
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts the command in its own session, and so its own
+// process group, so the shell and everything it spawns can be signalled
+// together. A new session also has no controlling terminal, otherwise an
+// interactive login shell in a background process group stops itself on
+// SIGTTIN when zapd is run from a terminal
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// terminateGroup asks every process in the group to shut down
+func terminateGroup(pgid int) error {
+	return signalGroup(pgid, syscall.SIGTERM)
+}
+
+// killGroup forcefully kills every process in the group
+func killGroup(pgid int) error {
+	return signalGroup(pgid, syscall.SIGKILL)
+}
+
+func signalGroup(pgid int, sig syscall.Signal) error {
+	err := syscall.Kill(-pgid, sig)
+	if err == syscall.ESRCH {
+		return nil
+	}
+	return err
+}
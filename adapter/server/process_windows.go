@@ -0,0 +1,30 @@
+package server
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup doesn't do anything, child processes are not tracked on
+// windows
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminateGroup kills the process as windows has no graceful signal
+func terminateGroup(pid int) error {
+	return killGroup(pid)
+}
+
+// killGroup kills the process
+func killGroup(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return nil
+	}
+	return process.Kill()
+}
+
+// groupPids doesn't do anything, child processes are not tracked on windows
+func groupPids(pgid int) []int { return []int{} }
+
+// processHasEnv isn't supported on windows so orphans are never recognised
+func processHasEnv(pid int, pair string) bool { return false }
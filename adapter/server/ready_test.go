@@ -0,0 +1,191 @@
+//go:build !windows
+// +build !windows
+
+package server
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	zadapter "github.com/moomerman/zap/adapter"
+)
+
+// lockedBuffer collects log output written from several goroutines
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// newReadyAdapter returns an adapter for a shell command, zapd listens on
+// the port itself with socket activation so it accepts connections without
+// the command having to
+func newReadyAdapter(command string, bootTimeout time.Duration) *adapter {
+	return New(&Config{
+		Host:             "ready.test",
+		ShellCommand:     command + " # %s %s",
+		RunMode:          RunShell,
+		Shell:            defaultShell,
+		ReadyPattern:     regexp.MustCompile("compiled successfully"),
+		SocketActivation: true,
+		BootTimeout:      bootTimeout,
+		StopTimeout:      time.Second,
+	}).(*adapter)
+}
+
+func waitForStatus(a *adapter, status zadapter.Status, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if a.Status() == status {
+			return true
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return false
+}
+
+func TestReadyPattern(t *testing.T) {
+	a := newReadyAdapter("echo compiled successfully >&2; sleep 1; echo compiled successfully; exec sleep 30", 5*time.Second)
+	if err := a.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer a.Stop(nil)
+
+	time.Sleep(700 * time.Millisecond)
+	if status := a.Status(); status != zadapter.StatusStarting {
+		t.Fatal("expected the app to wait for the pattern on stdout, got", status)
+	}
+
+	if !waitForStatus(a, zadapter.StatusRunning, 3*time.Second) {
+		t.Fatal("expected the app to be running once stdout matched, got", a.Status())
+	}
+}
+
+func TestReadyPatternTimeout(t *testing.T) {
+	logs := &lockedBuffer{}
+	log.SetOutput(logs)
+	defer log.SetOutput(os.Stderr)
+
+	a := newReadyAdapter("echo compiled successfully >&2; exec sleep 30", time.Second)
+	if err := a.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer a.Stop(nil)
+
+	if !waitForStatus(a, zadapter.StatusStopped, 5*time.Second) {
+		t.Fatal("expected the app to be stopped after the boot timeout, got", a.Status())
+	}
+	if !strings.Contains(logs.String(), "ready pattern timeout") {
+		t.Error("expected a ready pattern timeout, got", logs.String())
+	}
+}
+
+func TestHealthMonitor(t *testing.T) {
+	defer func(interval time.Duration) { healthCheckInterval = interval }(healthCheckInterval)
+	healthCheckInterval = 20 * time.Millisecond
+
+	var healthy, failures int32 = 1, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/up" {
+			http.NotFound(w, r)
+			return
+		}
+		if atomic.LoadInt32(&healthy) == 0 {
+			atomic.AddInt32(&failures, 1)
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := &adapter{Host: "health.test", Scheme: "http", ReadyPath: "/up", ReadyTimeout: time.Second}
+	a.changeState(zadapter.StatusRunning)
+
+	cancel := make(chan struct{})
+	defer close(cancel)
+	go a.healthMonitor(cancel, u.Port())
+
+	time.Sleep(100 * time.Millisecond)
+	if status := a.Status(); status != zadapter.StatusRunning {
+		t.Fatal("expected a healthy app to stay running, got", status)
+	}
+
+	atomic.StoreInt32(&healthy, 0)
+	if !waitForStatus(a, zadapter.StatusUnhealthy, 2*time.Second) {
+		t.Fatal("expected the app to be unhealthy, got", a.Status())
+	}
+	if n := atomic.LoadInt32(&failures); n < int32(healthCheckFailures) {
+		t.Error("expected", healthCheckFailures, "failed checks before the app was unhealthy, got", n)
+	}
+	a.Lock()
+	healthError := a.HealthError
+	a.Unlock()
+	if !strings.Contains(healthError, "503") {
+		t.Error("expected the failed check to be recorded, got", healthError)
+	}
+
+	atomic.StoreInt32(&healthy, 1)
+	if !waitForStatus(a, zadapter.StatusRunning, 2*time.Second) {
+		t.Fatal("expected the app to be running again, got", a.Status())
+	}
+	a.Lock()
+	healthError = a.HealthError
+	a.Unlock()
+	if healthError != "" {
+		t.Error("expected the health error to be cleared, got", healthError)
+	}
+}
+
+func TestCheckHealthStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := &adapter{Host: "health.test", Scheme: "http", ReadyPath: "/up", ReadyTimeout: time.Second}
+	if err := a.checkHealth(u.Port()); err != nil {
+		t.Error("expected any non-error status to pass, got", err)
+	}
+
+	a.ReadyStatus = http.StatusNoContent
+	if err := a.checkHealth(u.Port()); err != nil {
+		t.Error("expected the ready_status to pass, got", err)
+	}
+
+	a.ReadyStatus = http.StatusOK
+	if err := a.checkHealth(u.Port()); err == nil {
+		t.Error("expected an error when the status isn't ready_status")
+	}
+}
@@ -0,0 +1,266 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	zadapter "github.com/moomerman/zap/adapter"
+	"github.com/vektra/errors"
+)
+
+// drainTimeout is how long requests to the old web process have to finish
+// once requests have switched to its replacement
+const drainTimeout = 10 * time.Second
+
+// Replace boots a new web process on a fresh port or socket while the current
+// one keeps serving, switches requests to it once it's ready and then drains
+// and stops the old one. If the new process doesn't become ready it's stopped and the
+// old one keeps serving. It returns false without doing anything when the app
+// isn't running, or its output is spooled, so it has to be restarted instead
+func (a *adapter) Replace() (bool, error) {
+	a.Lock()
+	if a.replacing {
+		a.Unlock()
+		return true, errors.New("already replacing")
+	}
+	if a.cmd == nil || a.SpoolDir != "" || (a.state != zadapter.StatusRunning && a.state != zadapter.StatusUnhealthy) {
+		a.Unlock()
+		return false, nil
+	}
+
+	log.Println("[app]", a.Host, "REPLACE")
+	a.replacing = true
+	current := a.cmd
+	next, addr, err := a.startReplacement()
+	a.Unlock()
+
+	if err != nil {
+		return true, a.replaceFailed(err)
+	}
+
+	ready := make(chan struct{})
+	if a.ReadyPattern == nil {
+		close(ready)
+	}
+	go a.tailOutput(next, func() {
+		log.Println("[app]", a.Host, "ready pattern matched for replacement")
+		close(ready)
+	})
+
+	if err := a.waitForReplacement(addr, ready, next.drained); err != nil {
+		a.retire(next, nil)
+		a.releaseAddress(addr)
+		return true, a.replaceFailed(err)
+	}
+
+	a.Lock()
+	if a.cmd != current || a.state == zadapter.StatusStopping || a.state == zadapter.StatusStopped {
+		a.replacing = false
+		a.Unlock()
+		a.retire(next, nil)
+		a.releaseAddress(addr)
+		return true, errors.New("app stopped or restarted while replacing")
+	}
+
+	old := webOutput{
+		cmd:     a.cmd,
+		pid:     a.pgid,
+		cancel:  a.cancelChan,
+		drained: a.drained,
+	}
+	oldWait := a.wait
+	oldWorkers := a.Workers
+	oldAddr := a.address()
+
+	a.cmd = next.cmd
+	a.Pid = next.pid
+	a.pgid = next.pid
+	a.wait = func() { next.cmd.Wait() }
+	a.stdout = next.stdout
+	a.stderr = next.stderr
+	a.cancelChan = next.cancel
+	a.drained = next.drained
+	a.setAddress(addr)
+	a.ReplaceError = ""
+	a.HealthError = ""
+	a.replacing = false
+	a.changeState(zadapter.StatusRunning)
+
+	oldBackend := a.switchBackend(newBackend(a.Scheme, addr))
+	log.Println("[app]", a.Host, "switched to", addr, "draining", oldBackend.Addr)
+
+	// the old process is no longer watched for exiting or sampled
+	close(old.cancel)
+
+	a.Workers = []*worker{}
+	for _, process := range a.Processes {
+		w := newWorker(a, process)
+		a.Workers = append(a.Workers, w)
+		go w.run(a.logFile)
+	}
+
+	go a.sampleMetrics(a.cancelChan, a.Pid)
+	if a.ReadyPath != "" {
+		go a.healthMonitor(a.cancelChan, addr)
+	}
+
+	a.retiring.Add(1)
+	a.Unlock()
+
+	go func() {
+		defer a.retiring.Done()
+
+		if active := oldBackend.drain(drainTimeout); active > 0 {
+			log.Println("[app]", a.Host, active, "requests still in flight after", drainTimeout)
+		}
+		for _, w := range oldWorkers {
+			w.Stop()
+		}
+		a.retire(old, oldWait)
+		a.releaseAddress(oldAddr)
+	}()
+
+	return true, nil
+}
+
+// startReplacement starts a new web process on a fresh port or socket with
+// the env files read again, the caller must hold the lock
+func (a *adapter) startReplacement() (webOutput, string, error) {
+	var reservation *portReservation
+	var addr string
+	if a.SocketDir != "" {
+		path, err := a.newSocket(true)
+		if err != nil {
+			return webOutput{}, "", err
+		}
+		addr = path
+	} else {
+		r, err := ports.reservePort(a.Host)
+		if err != nil {
+			return webOutput{}, "", errors.Context(err, "couldn't find available port")
+		}
+		defer r.Close()
+		reservation = r
+		addr = r.Port
+	}
+
+	env, errs := readEnv(a.Dir, a.Env)
+	a.EnvErrors = []string{}
+	for _, err := range errs {
+		log.Println("[app]", a.Host, "ERROR", "couldn't read env", err)
+		a.EnvErrors = append(a.EnvErrors, err.Error())
+	}
+	a.env = env
+
+	command := fmt.Sprintf(a.ShellCommand, addr, a.Host)
+	cmd, err := a.newWebCommand(command, addr, reservation)
+	if err != nil {
+		a.releaseAddress(addr)
+		return webOutput{}, "", err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		a.releaseAddress(addr)
+		return webOutput{}, "", err
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		a.releaseAddress(addr)
+		return webOutput{}, "", err
+	}
+
+	if err := cmd.Start(); err != nil {
+		a.releaseAddress(addr)
+		return webOutput{}, "", errors.Context(err, "starting replacement")
+	}
+	a.limit(cmd.Process.Pid)
+
+	return webOutput{
+		cmd:     cmd,
+		pid:     cmd.Process.Pid,
+		stdout:  stdout,
+		stderr:  stderr,
+		logFile: a.logFile,
+		cancel:  make(chan struct{}),
+		drained: make(chan struct{}),
+	}, addr, nil
+}
+
+// waitForReplacement waits for the new web process to match the ready
+// pattern, listen on its port or socket and pass the health check
+func (a *adapter) waitForReplacement(addr string, ready, exited <-chan struct{}) error {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(a.BootTimeout)
+
+	for {
+		select {
+		case <-exited:
+			return errors.New("replacement exited while booting")
+		case <-timeout:
+			return errors.Format("replacement not ready after %s", a.BootTimeout)
+		case <-ticker.C:
+			select {
+			case <-ready:
+			default:
+				continue
+			}
+
+			c, err := dialAddress(context.Background(), addr)
+			if err != nil {
+				continue
+			}
+			c.Close()
+
+			if err := a.checkHealth(addr); err != nil {
+				log.Println("[app]", a.Host, "replacement", addr, "is available but not ready", err)
+				continue
+			}
+
+			log.Println("[app]", a.Host, "replacement", addr, "is available")
+			return nil
+		}
+	}
+}
+
+// retire stops following a web process and stops its process group, waiting
+// for the last of its output to be logged. wait, if given, reaps the leader
+func (a *adapter) retire(out webOutput, wait func()) {
+	select {
+	case <-out.cancel:
+	default:
+		close(out.cancel)
+	}
+
+	if wait == nil {
+		wait = func() { out.cmd.Wait() }
+	}
+
+	killed, err := stopGroup(out.pid, wait, a.StopTimeout)
+	if err != nil {
+		log.Println("[app]", a.Host, "error trying to stop old process", out.pid, err)
+	}
+	if len(killed) > 0 {
+		log.Println("[app]", a.Host, "old processes still alive after", a.StopTimeout, killed, "killed")
+	}
+
+	select {
+	case <-out.drained:
+	case <-time.After(time.Second):
+	}
+}
+
+func (a *adapter) replaceFailed(err error) error {
+	a.Lock()
+	defer a.Unlock()
+
+	log.Println("[app]", a.Host, "ERROR", "replacement failed, still serving from", a.address(), err)
+	a.replacing = false
+	a.ReplaceError = strings.TrimSpace(err.Error())
+	return err
+}
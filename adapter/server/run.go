@@ -0,0 +1,223 @@
+package server
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Run modes control how the app command is started
+const (
+	// RunLogin runs the command in a login interactive shell so the user's rc
+	// files set up the environment, this is the default
+	RunLogin = "login"
+	// RunShell runs the command with the configured shell and arguments
+	RunShell = "shell"
+	// RunExec runs the command directly without a shell
+	RunExec = "exec"
+)
+
+// defaultShell is used when SHELL isn't set, eg. when running under systemd
+const defaultShell = "/bin/sh"
+
+// shimDirs are the version manager shim directories added to the PATH when
+// not running in a login shell, each is the env var that overrides the root
+// and the default root relative to the home directory
+var shimDirs = []struct {
+	Env     string
+	Default string
+}{
+	{"ASDF_DATA_DIR", ".asdf"},
+	{"MISE_DATA_DIR", ".local/share/mise"},
+	{"RBENV_ROOT", ".rbenv"},
+	{"NODENV_ROOT", ".nodenv"},
+	{"PYENV_ROOT", ".pyenv"},
+}
+
+// buildCommand returns the command to run for the app run mode with the
+// given environment
+func (a *adapter) buildCommand(command string, env []string) *exec.Cmd {
+	shell := a.Shell
+	if shell == "" {
+		shell = getenv(env, "SHELL")
+	}
+	if shell == "" {
+		shell = defaultShell
+	}
+
+	switch a.RunMode {
+	case RunExec:
+		env = withShims(env)
+		args := splitCommand(command, func(key string) string { return getenv(env, key) })
+		if len(args) == 0 {
+			args = []string{command}
+		}
+		cmd := exec.Command(lookPath(args[0], getenv(env, "PATH")), args[1:]...)
+		cmd.Env = env
+		return cmd
+
+	case RunShell:
+		env = withShims(env)
+		args := a.ShellArgs
+		if len(args) == 0 {
+			args = []string{"-c"}
+		}
+		cmd := exec.Command(shell, append(append([]string{}, args...), command)...)
+		cmd.Env = env
+		return cmd
+	}
+
+	cmd := exec.Command(shell, "-l", "-i", "-c", command)
+	cmd.Env = env
+	return cmd
+}
+
+// withShims prepends any version manager shim directories that exist to the
+// PATH so tools resolve without the shell rc files
+func withShims(env []string) []string {
+	home := getenv(env, "HOME")
+
+	dirs := []string{}
+	for _, shim := range shimDirs {
+		root := getenv(env, shim.Env)
+		if root == "" {
+			if home == "" {
+				continue
+			}
+			root = filepath.Join(home, shim.Default)
+		}
+
+		dir := filepath.Join(root, "shims")
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	if len(dirs) == 0 {
+		return env
+	}
+
+	path := strings.Join(dirs, string(os.PathListSeparator))
+	if current := getenv(env, "PATH"); current != "" {
+		path += string(os.PathListSeparator) + current
+	}
+	return append(env, "PATH="+path)
+}
+
+// lookPath finds the executable in the given PATH rather than the PATH of
+// zap itself, names containing a slash are returned as they are
+func lookPath(name, path string) string {
+	if strings.Contains(name, "/") {
+		return name
+	}
+
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			dir = "."
+		}
+		file := filepath.Join(dir, name)
+		if info, err := os.Stat(file); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return file
+		}
+	}
+	return name
+}
+
+// getenv returns the last value of key in env
+func getenv(env []string, key string) string {
+	for i := len(env) - 1; i >= 0; i-- {
+		if strings.HasPrefix(env[i], key+"=") {
+			return env[i][len(key)+1:]
+		}
+	}
+	return ""
+}
+
+// splitCommand splits a command into arguments the way a shell would,
+// handling quotes, backslashes and $VAR outside single quotes, stopping at a
+// comment and dropping a leading exec
+func splitCommand(command string, lookup func(string) string) []string {
+	args := []string{}
+	var arg strings.Builder
+	inArg := false
+	var quote byte
+
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				arg.WriteByte(c)
+			}
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else if c == '\\' && i+1 < len(command) && strings.IndexByte(`"\$`, command[i+1]) >= 0 {
+				i++
+				arg.WriteByte(command[i])
+			} else if c == '$' {
+				i = expandVar(command, i, &arg, lookup)
+			} else {
+				arg.WriteByte(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inArg = true
+		case c == '\\' && i+1 < len(command):
+			i++
+			arg.WriteByte(command[i])
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		case c == '#' && !inArg:
+			i = len(command)
+		case c == '$':
+			i = expandVar(command, i, &arg, lookup)
+			inArg = true
+		default:
+			arg.WriteByte(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	if len(args) > 0 && args[0] == "exec" {
+		args = args[1:]
+	}
+	return args
+}
+
+// expandVar writes the value of the $VAR or ${VAR} at command[i] to arg and
+// returns the index of its last character, a lone $ is written as it is
+func expandVar(command string, i int, arg *strings.Builder, lookup func(string) string) int {
+	rest := command[i+1:]
+
+	if strings.HasPrefix(rest, "{") {
+		if end := strings.IndexByte(rest, '}'); end > 0 {
+			arg.WriteString(lookup(rest[1:end]))
+			return i + 1 + end
+		}
+	}
+
+	n := 0
+	for n < len(rest) && (rest[n] == '_' || isAlphaNum(rest[n])) {
+		n++
+	}
+	if n == 0 {
+		arg.WriteByte('$')
+		return i
+	}
+
+	arg.WriteString(lookup(rest[:n]))
+	return i + n
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	env := map[string]string{"PORT": "4000", "NAME": "moo"}
+	lookup := func(key string) string { return env[key] }
+
+	tests := map[string][]string{
+		"exec mix phx.server # 4000 moo.test":   {"mix", "phx.server"},
+		`bin/rails s -p $PORT -b '$HOST'`:       {"bin/rails", "s", "-p", "4000", "-b", "$HOST"},
+		`./app --name="${NAME} app" a\ b "\$x"`: {"./app", "--name=moo app", "a b", "$x"},
+		"echo $ a#b":                            {"echo", "$", "a#b"},
+	}
+
+	for command, expected := range tests {
+		if args := splitCommand(command, lookup); !reflect.DeepEqual(args, expected) {
+			t.Errorf("%s: expected %q, got %q", command, expected, args)
+		}
+	}
+}
+
+func TestBuildCommand(t *testing.T) {
+	home, err := ioutil.TempDir("", "zap-run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	shims := filepath.Join(home, ".asdf", "shims")
+	os.MkdirAll(shims, 0755)
+	if err := ioutil.WriteFile(filepath.Join(shims, "node"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	env := []string{"HOME=" + home, "PATH=/usr/bin:/bin"}
+
+	a := &adapter{RunMode: RunExec}
+	cmd := a.buildCommand("exec node server.js # 4000 moo.test", env)
+	if cmd.Path != filepath.Join(shims, "node") || !reflect.DeepEqual(cmd.Args[1:], []string{"server.js"}) {
+		t.Error("expected node from the asdf shims, got", cmd.Path, cmd.Args)
+	}
+
+	a = &adapter{RunMode: RunShell, Shell: "/bin/bash", ShellArgs: []string{"--norc", "-c"}}
+	cmd = a.buildCommand("npm start", env)
+	if !reflect.DeepEqual(cmd.Args, []string{"/bin/bash", "--norc", "-c", "npm start"}) {
+		t.Error("unexpected shell args", cmd.Args)
+	}
+
+	a = &adapter{}
+	cmd = a.buildCommand("npm start", env)
+	if !reflect.DeepEqual(cmd.Args, []string{defaultShell, "-l", "-i", "-c", "npm start"}) {
+		t.Error("expected a login shell without SHELL set, got", cmd.Args)
+	}
+}
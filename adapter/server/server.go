@@ -3,78 +3,209 @@ package server
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
-	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"sync"
 	"time"
 
 	zadapter "github.com/moomerman/zap/adapter"
-	"github.com/moomerman/zap/rproxy"
-	"github.com/puma/puma-dev/linebuffer"
+	"github.com/moomerman/zap/logfile"
 	"github.com/vektra/errors"
 )
 
 // Config holds the server configuration
 type Config struct {
-	Name            string
-	Scheme          string
-	Host            string
-	Dir             string
-	EnvPortName     string
-	ShellCommand    string
-	RestartPatterns []*regexp.Regexp
+	Name             string
+	Scheme           string
+	Host             string
+	Dir              string
+	EnvPortName      string
+	ShellCommand     string
+	RunMode          string
+	Shell            string
+	ShellArgs        []string
+	RestartPatterns  []RestartPattern
+	BootTimeout      time.Duration
+	ReadyPath        string
+	ReadyStatus      int
+	ReadyTimeout     time.Duration
+	ReadyPattern     *regexp.Regexp
+	StopTimeout      time.Duration
+	LogFile          string
+	LogMaxSize       int64
+	LogMaxFiles      int
+	OnLog            func(line zadapter.LogLine)
+	Processes        []Process
+	Env              map[string]string
+	Limits           Limits
+	SpoolDir         string
+	AdoptPid         int
+	AdoptPort        string
+	AdoptSocket      string
+	SocketActivation bool
+	SocketDir        string
 }
 
+// RestartPattern restarts the app when a line of output matches, optionally
+// only on the given stream (stdout or stderr)
+type RestartPattern struct {
+	Pattern *regexp.Regexp
+	Stream  string
+}
+
+// webProcess is the name of the process that is given the port and proxied to
+const webProcess = "web"
+
+// DefaultBootTimeout is how long an app has to start listening on its port
+const DefaultBootTimeout = 60 * time.Second
+
+// DefaultStopTimeout is how long an app has to shut down before it is killed
+const DefaultStopTimeout = 5 * time.Second
+
+// DefaultReadyTimeout is how long a single health check request can take
+const DefaultReadyTimeout = 5 * time.Second
+
+// how often a running app's ReadyPath is checked and how many checks in a
+// row have to fail before it's marked unhealthy
+var (
+	healthCheckInterval = 5 * time.Second
+	healthCheckFailures = 3
+)
+
 // New returns a new server adapter
 func New(config *Config) zadapter.Adapter {
+	bootTimeout := config.BootTimeout
+	if bootTimeout == 0 {
+		bootTimeout = DefaultBootTimeout
+	}
+
+	stopTimeout := config.StopTimeout
+	if stopTimeout == 0 {
+		stopTimeout = DefaultStopTimeout
+	}
+
+	readyTimeout := config.ReadyTimeout
+	if readyTimeout == 0 {
+		readyTimeout = DefaultReadyTimeout
+	}
+
 	return &adapter{
-		Name:            config.Name,
-		Scheme:          config.Scheme,
-		Host:            config.Host,
-		Dir:             config.Dir,
-		EnvPortName:     config.EnvPortName,
-		ShellCommand:    config.ShellCommand,
-		RestartPatterns: config.RestartPatterns,
+		Name:             config.Name,
+		Scheme:           config.Scheme,
+		Host:             config.Host,
+		Dir:              config.Dir,
+		EnvPortName:      config.EnvPortName,
+		ShellCommand:     config.ShellCommand,
+		RunMode:          config.RunMode,
+		Shell:            config.Shell,
+		ShellArgs:        config.ShellArgs,
+		RestartPatterns:  config.RestartPatterns,
+		BootTimeout:      bootTimeout,
+		ReadyPath:        config.ReadyPath,
+		ReadyStatus:      config.ReadyStatus,
+		ReadyTimeout:     readyTimeout,
+		ReadyPattern:     config.ReadyPattern,
+		StopTimeout:      stopTimeout,
+		LogFile:          config.LogFile,
+		LogMaxSize:       config.LogMaxSize,
+		LogMaxFiles:      config.LogMaxFiles,
+		Processes:        config.Processes,
+		Env:              config.Env,
+		Limits:           config.Limits,
+		SpoolDir:         config.SpoolDir,
+		adoptPid:         config.AdoptPid,
+		adoptPort:        config.AdoptPort,
+		adoptSocket:      config.AdoptSocket,
+		SocketActivation: config.SocketActivation,
+		SocketDir:        config.SocketDir,
+		onLog:            config.OnLog,
 	}
 }
 
 type adapter struct {
 	sync.Mutex
 
-	Name            string
-	Scheme          string
-	Host            string
-	Dir             string
-	Port            string
-	Command         string
-	EnvPortName     string           `json:",omitempty"`
-	RestartPatterns []*regexp.Regexp `json:",omitempty"`
-	BootLog         string
-	Pid             int
-	ShellCommand    string
-
-	stateMu    sync.Mutex
-	state      zadapter.Status
-	cmd        *exec.Cmd
-	proxiesMu  sync.Mutex
-	proxies    map[string]*rproxy.ReverseProxy
-	stdout     io.Reader
-	log        linebuffer.LineBuffer
-	cancelChan chan struct{}
+	Name             string
+	Scheme           string
+	Host             string
+	Dir              string
+	Port             string
+	Command          string
+	EnvPortName      string           `json:",omitempty"`
+	RestartPatterns  []RestartPattern `json:"-"`
+	Restarts         int
+	RestartLine      string `json:",omitempty"`
+	BootLog          string
+	BootTimeout      time.Duration
+	ReadyPath        string         `json:",omitempty"`
+	ReadyStatus      int            `json:",omitempty"`
+	ReadyTimeout     time.Duration  `json:",omitempty"`
+	ReadyPattern     *regexp.Regexp `json:"-"`
+	HealthError      string         `json:",omitempty"`
+	StopTimeout      time.Duration
+	KilledPids       []int  `json:",omitempty"`
+	LogFile          string `json:",omitempty"`
+	LogMaxSize       int64  `json:"-"`
+	LogMaxFiles      int    `json:"-"`
+	Pid              int
+	ShellCommand     string
+	RunMode          string            `json:",omitempty"`
+	Shell            string            `json:",omitempty"`
+	ShellArgs        []string          `json:",omitempty"`
+	Processes        []Process         `json:"-"`
+	Workers          []*worker         `json:",omitempty"`
+	Env              map[string]string `json:"-"`
+	EnvErrors        []string          `json:",omitempty"`
+	Resources        *metrics          `json:",omitempty"`
+	Limits           Limits
+	StopReason       string `json:",omitempty"`
+	SpoolDir         string `json:",omitempty"`
+	ReplaceError     string `json:",omitempty"`
+	SocketActivation bool   `json:",omitempty"`
+	SocketDir        string `json:",omitempty"`
+	Socket           string `json:",omitempty"`
+
+	stateMu      sync.Mutex
+	state        zadapter.Status
+	ready        bool
+	cmd          *exec.Cmd
+	proxiesMu    sync.Mutex
+	backend      *backend
+	stdout       io.Reader
+	stderr       io.Reader
+	log          logBuffer
+	logFile      *logfile.File
+	onLog        func(line zadapter.LogLine)
+	cancelChan   chan struct{}
+	drained      chan struct{}
+	env          []string
+	cgroup       *cgroup
+	pgid         int
+	wait         func()
+	adoptPid     int
+	adoptPort    string
+	adoptSocket  string
+	replacing    bool
+	replacements int
+	retiring     sync.WaitGroup
 }
 
 // Start starts the application
 func (a *adapter) Start() error {
 	a.Lock()
 	defer a.Unlock()
-	if a.state == zadapter.StatusStopping || a.state == zadapter.StatusRunning {
+	if a.state == zadapter.StatusStopping || a.state == zadapter.StatusRunning || a.state == zadapter.StatusUnhealthy {
 		return nil
 	}
 
@@ -91,6 +222,9 @@ func (a *adapter) Stop(reason error) error {
 	}
 
 	log.Println("[app]", a.Host, "STOP", reason)
+	if reason != nil && a.StopReason == "" {
+		a.StopReason = reason.Error()
+	}
 	return a.stop()
 }
 
@@ -106,9 +240,70 @@ func (a *adapter) WriteLog(w io.Writer) {
 	a.log.WriteTo(w)
 }
 
+// LogLines returns the buffered log lines tagged with their stream
+func (a *adapter) LogLines() []zadapter.LogLine {
+	return a.log.Lines()
+}
+
+// ProcessStatus returns the status of the processes running alongside the
+// web process
+func (a *adapter) ProcessStatus() []zadapter.ProcessStatus {
+	a.Lock()
+	defer a.Unlock()
+	statuses := []zadapter.ProcessStatus{}
+	for _, w := range a.Workers {
+		statuses = append(statuses, w.status())
+	}
+	return statuses
+}
+
+// Killed returns the pids that had to be killed when the app and its
+// processes were last stopped
+func (a *adapter) Killed() []int {
+	a.Lock()
+	defer a.Unlock()
+	killed := append([]int{}, a.KilledPids...)
+	for _, w := range a.Workers {
+		killed = append(killed, w.status().KilledPids...)
+	}
+	return killed
+}
+
+// Reason returns why the app was last stopped
+func (a *adapter) Reason() string {
+	a.Lock()
+	defer a.Unlock()
+	return a.StopReason
+}
+
+// Metrics returns the recent resource samples for the app process tree
+func (a *adapter) Metrics() []zadapter.ProcessMetrics {
+	a.Lock()
+	defer a.Unlock()
+	if a.Resources == nil {
+		return nil
+	}
+	return a.Resources.Samples()
+}
+
+// Warnings returns problems with the app setup that didn't stop it starting,
+// such as env files that couldn't be parsed
+func (a *adapter) Warnings() []string {
+	a.Lock()
+	defer a.Unlock()
+	warnings := append([]string{}, a.EnvErrors...)
+	if a.ReplaceError != "" {
+		warnings = append(warnings, "zero downtime restart failed, still running the previous process: "+a.ReplaceError)
+	}
+	return warnings
+}
+
 // ServeHTTP implements the http.Handler interface
 func (a *adapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	proxy, err := a.getProxy(r.Host)
+	backend := a.acquireBackend()
+	defer backend.release()
+
+	proxy, err := backend.getProxy(r.Host)
 	if err != nil {
 		log.Println("[app]", a.Host, "error trying get proxy", err)
 		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
@@ -120,26 +315,96 @@ func (a *adapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 func (a *adapter) start() error {
 	a.changeState(zadapter.StatusStarting)
+	a.setReady(a.ReadyPattern == nil)
+	a.cmd = nil
+	a.StopReason = ""
 	a.cancelChan = make(chan struct{})
 
-	port, err := findAvailablePort()
-	if err != nil {
-		e := errors.Context(err, "couldn't find available port")
-		a.error(e)
-		return e
+	adoptPid := a.adoptPid
+	a.adoptPid = 0
+
+	var reservation *portReservation
+	if adoptPid != 0 {
+		a.Port = a.adoptPort
+		a.Socket = a.adoptSocket
+		if a.Socket == "" {
+			ports.claimPort(a.Host, a.Port)
+		}
+	} else if a.SocketDir != "" {
+		path, err := a.newSocket(false)
+		if err != nil {
+			a.error(err)
+			return err
+		}
+		a.setAddress(path)
+	} else {
+		r, err := ports.reservePort(a.Host)
+		if err != nil {
+			e := errors.Context(err, "couldn't find available port")
+			a.error(e)
+			return e
+		}
+		// the port is held until the app has started
+		defer r.Close()
+		reservation = r
+		a.setAddress(r.Port)
 	}
 
-	a.Port = port
+	if a.LogFile != "" {
+		logFile, err := logfile.Open(a.LogFile, a.LogMaxSize, a.LogMaxFiles)
+		if err != nil {
+			log.Println("[app]", a.Host, "ERROR", "couldn't open log file", err)
+		}
+		a.logFile = logFile
+	}
 
-	log.Println("[app] command:", a.ShellCommand)
-	if err := a.startApplication(a.ShellCommand); err != nil {
-		e := errors.Context(err, "could not start application")
-		a.error(e)
-		return e
+	env, errs := readEnv(a.Dir, a.Env)
+	a.EnvErrors = []string{}
+	for _, err := range errs {
+		log.Println("[app]", a.Host, "ERROR", "couldn't read env", err)
+		a.EnvErrors = append(a.EnvErrors, err.Error())
+	}
+	for _, pair := range env {
+		log.Println("[app]", a.Host, "INFO", "added env var", strings.SplitN(pair, "=", 2)[0])
 	}
+	a.env = env
 
-	a.proxies = make(map[string]*rproxy.ReverseProxy)
+	if !a.Limits.Empty() {
+		cgroup, err := newCgroup(a.Host, a.Limits)
+		if err != nil {
+			log.Println("[app]", a.Host, "INFO", "cgroup limits unavailable, cpu_quota is not enforced and max_memory is checked by sampling", err)
+		}
+		a.cgroup = cgroup
+	}
 
+	if adoptPid != 0 {
+		if err := a.adoptApplication(adoptPid); err != nil {
+			e := errors.Context(err, "could not adopt application")
+			a.error(e)
+			return e
+		}
+	} else {
+		log.Println("[app] command:", a.ShellCommand)
+		if err := a.startApplication(a.ShellCommand, reservation); err != nil {
+			e := errors.Context(err, "could not start application")
+			a.error(e)
+			return e
+		}
+	}
+
+	a.switchBackend(newBackend(a.Scheme, a.address()))
+
+	a.Workers = []*worker{}
+	for _, process := range a.Processes {
+		w := newWorker(a, process)
+		a.Workers = append(a.Workers, w)
+		go w.run(a.logFile)
+	}
+
+	a.Resources = &metrics{}
+	go a.sampleMetrics(a.cancelChan, a.Pid)
+
+	a.drained = make(chan struct{})
 	go a.tail()
 	go a.checkPort()
 
@@ -150,13 +415,52 @@ func (a *adapter) stop() error {
 	a.changeState(zadapter.StatusStopping)
 	defer close(a.cancelChan)
 
-	err := a.cmd.Process.Kill()
-	if err != nil {
-		log.Println("[app]", a.Host, "error trying to stop", err)
-		return err
+	var wg sync.WaitGroup
+	for _, w := range a.Workers {
+		wg.Add(1)
+		go func(w *worker) {
+			defer wg.Done()
+			w.Stop()
+		}(w)
 	}
 
-	a.cmd.Wait()
+	if a.pgid != 0 {
+		killed, err := stopGroup(a.pgid, a.wait, a.StopTimeout)
+		if err != nil {
+			log.Println("[app]", a.Host, "error trying to stop", err)
+			wg.Wait()
+			return err
+		}
+		a.KilledPids = killed
+		if len(killed) > 0 {
+			log.Println("[app]", a.Host, "processes still alive after", a.StopTimeout, killed, "killed")
+		}
+	}
+
+	wg.Wait()
+	a.pgid = 0
+	a.releaseAddress(a.address())
+
+	if err := a.cgroup.Remove(); err != nil {
+		log.Println("[app]", a.Host, "error removing cgroup", err)
+	}
+	a.cgroup = nil
+
+	// a process replaced by a zero downtime restart may still be stopping
+	a.retiring.Wait()
+
+	// give the last of the output a moment to reach the log before closing it
+	if a.drained != nil {
+		select {
+		case <-a.drained:
+		case <-time.After(time.Second):
+		}
+		a.drained = nil
+	}
+
+	if a.logFile != nil {
+		a.logFile.Close()
+	}
 
 	log.Println("[app]", a.Host, "shutdown and cleaned up")
 	a.changeState(zadapter.StatusStopped)
@@ -164,6 +468,52 @@ func (a *adapter) stop() error {
 	return nil
 }
 
+// stopGroup sends SIGTERM to the process group, waits up to timeout for it
+// to exit and then kills any processes that are still alive, returning their
+// pids. wait, if given, reaps the group leader
+func stopGroup(pgid int, wait func(), timeout time.Duration) ([]int, error) {
+	if err := terminateGroup(pgid); err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		if wait != nil {
+			wait()
+		}
+		close(done)
+	}()
+
+	killed := waitForGroup(pgid, timeout)
+	if len(killed) > 0 {
+		if err := killGroup(pgid); err != nil {
+			log.Println("[app]", "error trying to kill process group", pgid, err)
+		}
+	}
+
+	<-done
+	return killed, nil
+}
+
+// waitForGroup waits up to timeout for every process in the group to exit
+// and returns the ones that are still alive
+func waitForGroup(pgid int, timeout time.Duration) []int {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(timeout)
+
+	for {
+		select {
+		case <-ticker.C:
+			if len(groupPids(pgid)) == 0 {
+				return nil
+			}
+		case <-deadline:
+			return groupPids(pgid)
+		}
+	}
+}
+
 func (a *adapter) error(err error) error {
 	if a.state == zadapter.StatusStopping || a.state == zadapter.StatusStopped {
 		return nil
@@ -179,28 +529,17 @@ func (a *adapter) error(err error) error {
 	return nil
 }
 
-func (a *adapter) startApplication(command string) error {
-	shell := os.Getenv("SHELL")
-
-	command = fmt.Sprintf(command, a.Port, a.Host)
+func (a *adapter) startApplication(command string, reservation *portReservation) error {
+	command = fmt.Sprintf(command, a.address(), a.Host)
 	a.Command = command
 
-	cmd := exec.Command(shell, "-l", "-i", "-c", command)
-	cmd.Dir = a.Dir
-
-	cmd.Env = os.Environ()
-	if a.EnvPortName != "" {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", a.EnvPortName, a.Port))
-	}
-
-	appEnv, err := readEnvFile(cmd.Dir)
+	cmd, err := a.newWebCommand(command, a.address(), reservation)
 	if err != nil {
-		log.Println("[app]", a.Host, "ERROR", "couldn't read env file", err)
+		return err
 	}
 
-	for _, pair := range appEnv {
-		log.Println("[app]", a.Host, "INFO", "added env var", pair)
-		cmd.Env = append(cmd.Env, pair)
+	if a.SpoolDir != "" {
+		return a.startSpooled(cmd)
 	}
 
 	stdout, err := cmd.StdoutPipe()
@@ -208,57 +547,213 @@ func (a *adapter) startApplication(command string) error {
 		return err
 	}
 
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
 	a.stdout = stdout
-	cmd.Stderr = cmd.Stdout
+	a.stderr = stderr
 
 	if err = cmd.Start(); err != nil {
 		return errors.Context(err, "starting app")
 	}
 
 	a.Pid = cmd.Process.Pid
+	a.pgid = cmd.Process.Pid
+	a.wait = func() { cmd.Wait() }
 	a.cmd = cmd
+	a.limit(cmd.Process.Pid)
 	return nil
 }
 
+// limit applies the limits that can only be set once the process is running
+func (a *adapter) limit(pid int) {
+	if err := a.cgroup.Add(pid); err != nil {
+		log.Println("[app]", a.Host, "error adding process to cgroup", pid, err)
+	}
+}
+
+// newWebCommand builds the web process command for the port or socket path it
+// listens on, with the reserved port's socket passed to it when socket
+// activation is on. The reservation has to be closed once the command has
+// started
+func (a *adapter) newWebCommand(command, addr string, reservation *portReservation) (*exec.Cmd, error) {
+	cmd := a.newCommand(command, addr)
+	if reservation == nil {
+		return cmd, nil
+	}
+	if !a.SocketActivation {
+		// closed now rather than after the start so the app can bind it
+		reservation.Close()
+		return cmd, nil
+	}
+	return withListener(cmd, reservation)
+}
+
+// newCommand builds a command that runs in the app directory with the app
+// environment in its own process group, the port or socket path is only
+// given to the web process
+func (a *adapter) newCommand(command, addr string) *exec.Cmd {
+	env := os.Environ()
+	if a.EnvPortName != "" && addr != "" {
+		env = append(env, fmt.Sprintf("%s=%s", a.EnvPortName, addr))
+	}
+	env = append(env, appEnvMarker+"="+a.Host)
+	env = append(env, a.env...)
+
+	cmd := withLimits(a.buildCommand(command, env), a.Limits)
+	cmd.Dir = a.Dir
+	setProcessGroup(cmd)
+	return cmd
+}
+
+// appendLog records a line of output from one of the app processes
+func (a *adapter) appendLog(logFile *logfile.File, pid int, line zadapter.LogLine) {
+	a.log.Append(line)
+	if a.onLog != nil {
+		a.onLog(line)
+	}
+
+	tag := line.Stream
+	if line.Process != webProcess {
+		tag = line.Process + "/" + line.Stream
+	}
+
+	if logFile != nil {
+		logFile.WriteLine(line.Time, tag+" "+line.Text)
+	} else {
+		fmt.Fprintf(os.Stdout, "  [%s] %s:%s[%d]: %s", tag, a.Host, filepath.Base(a.address()), pid, line.Text)
+	}
+}
+
+// webOutput is the output of a web process, cancel stops it being followed
+// and drained is closed once all of it has been read
+type webOutput struct {
+	cmd     *exec.Cmd
+	pid     int
+	stdout  io.Reader
+	stderr  io.Reader
+	logFile *logfile.File
+	cancel  chan struct{}
+	drained chan struct{}
+}
+
 func (a *adapter) tail() {
-	c := make(chan error)
+	out := webOutput{
+		cmd:     a.cmd,
+		pid:     a.Pid,
+		stdout:  a.stdout,
+		stderr:  a.stderr,
+		logFile: a.logFile,
+		cancel:  a.cancelChan,
+		drained: a.drained,
+	}
 
-	go func() {
-		r := bufio.NewReader(a.stdout)
+	a.tailOutput(out, func() {
+		log.Println("[app]", a.Host, "ready pattern matched")
+		a.setReady(true)
+	})
+}
+
+// tailOutput logs the output of a web process, calling ready the first time
+// a stdout line matches the ready pattern, and stops the adapter when the output
+// closes if the process is still the current one
+func (a *adapter) tailOutput(out webOutput, ready func()) {
+	cmd := out.cmd
+	var readyOnce sync.Once
+
+	var wg sync.WaitGroup
+	read := func(stream string, r io.Reader) {
+		defer wg.Done()
+		br := bufio.NewReader(r)
 
 		for {
-			line, err := r.ReadString('\n')
-			if line != "" {
-				a.log.Append(line)
-				fmt.Fprintf(os.Stdout, "  [log] %s:%s[%d]: %s", a.Host, a.Port, a.cmd.Process.Pid, line)
+			text, err := br.ReadString('\n')
+			if text != "" {
+				a.appendLog(out.logFile, out.pid, zadapter.LogLine{Time: time.Now(), Process: webProcess, Stream: stream, Text: text})
+
+				// progress is often written to stderr, only stdout says
+				// the app is ready
+				if stream == zadapter.StreamStdout && a.ReadyPattern != nil && a.ReadyPattern.MatchString(text) {
+					readyOnce.Do(ready)
+				}
 
 				for _, pattern := range a.RestartPatterns {
-					if pattern.MatchString(line) {
-						a.Stop(errors.New("Restart pattern matched"))
+					if pattern.Stream != "" && pattern.Stream != stream {
+						continue
+					}
+					if pattern.Pattern.MatchString(text) && a.restart(cmd, text) {
 						return
 					}
 				}
 			}
 
 			if err != nil {
-				c <- err
 				return
 			}
 		}
-	}()
+	}
 
-	var err error
+	wg.Add(2)
+	go read(zadapter.StreamStdout, out.stdout)
+	go read(zadapter.StreamStderr, out.stderr)
+
+	c := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(out.drained)
+		close(c)
+	}()
 
 	select {
-	case err = <-c:
-		a.Stop(errors.Context(err, "stdout/stderr closed"))
+	case <-c:
+		a.exited(cmd)
+	case <-out.cancel:
+	}
+}
+
+// exited stops the adapter once the output of the given command has closed,
+// unless the command has already been replaced by a restart
+func (a *adapter) exited(cmd *exec.Cmd) {
+	a.Lock()
+	defer a.Unlock()
+	if a.cmd != cmd || a.state == zadapter.StatusStopping || a.state == zadapter.StatusStopped {
+		return
 	}
 
+	log.Println("[app]", a.Host, "STOP", "stdout/stderr closed")
+	a.StopReason = "exited"
+	if a.cgroup.OOMKilled() {
+		a.StopReason = "killed for exceeding max_memory"
+	}
+	a.stop()
+}
+
+// restart stops and starts the application after a restart pattern matched
+// a line of output from the given command, reporting whether it did
+func (a *adapter) restart(cmd *exec.Cmd, line string) bool {
+	a.Lock()
+	defer a.Unlock()
+	if a.cmd != cmd || a.state == zadapter.StatusStopping || a.state == zadapter.StatusStopped {
+		return false
+	}
+
+	line = strings.TrimSpace(line)
+	log.Println("[app]", a.Host, "RESTART", "restart pattern matched", line)
+	a.Restarts++
+	a.RestartLine = line
+
+	if err := a.stop(); err != nil {
+		return true
+	}
+	a.start()
+	return true
 }
 
 func (a *adapter) checkPort() {
 	ticker := time.NewTicker(250 * time.Millisecond)
-	timeout := time.After(time.Second * 60)
+	timeout := time.After(a.BootTimeout)
 	defer ticker.Stop()
 
 	for {
@@ -267,66 +762,156 @@ func (a *adapter) checkPort() {
 			log.Println("[app]", a.Host, "cancel channel closed")
 			return
 		case <-ticker.C:
-			c, err := net.Dial("tcp", ":"+a.Port)
-			if err == nil {
-				defer c.Close()
-				log.Println("[app]", a.Host, "port", a.Port, "is available")
-				buf := bytes.NewBufferString("")
-				a.WriteLog(buf)
-				a.BootLog = buf.String()
-				a.changeState(zadapter.StatusRunning)
-				return
+			if !a.isReady() {
+				continue
 			}
+
+			addr := a.address()
+			c, err := dialAddress(context.Background(), addr)
 			if err != nil {
-				log.Println("[app]", a.Host, "error checking port", a.Port, err)
+				log.Println("[app]", a.Host, "error checking", addr, err)
+				continue
+			}
+			c.Close()
+
+			if err := a.checkHealth(addr); err != nil {
+				log.Println("[app]", a.Host, addr, "is available but not ready", err)
+				continue
+			}
+
+			log.Println("[app]", a.Host, addr, "is available")
+			buf := bytes.NewBufferString("")
+			a.WriteLog(buf)
+			a.BootLog = buf.String()
+			a.changeState(zadapter.StatusRunning)
+
+			if a.ReadyPath != "" {
+				go a.healthMonitor(a.cancelChan, addr)
 			}
+			return
 		case <-timeout:
-			log.Println("[app]", a.Host, "timeout waiting for port", a.Port)
+			a.Lock()
+			defer a.Unlock()
+			if !a.isReady() {
+				log.Println("[app]", a.Host, "timeout waiting for ready pattern", a.ReadyPattern)
+				a.error(errors.New("ready pattern timeout"))
+				return
+			}
+			log.Println("[app]", a.Host, "timeout waiting for", a.address())
 			a.error(errors.New("check port timeout"))
 			return
 		}
 	}
 }
 
-func (a *adapter) changeState(state zadapter.Status) {
-	a.stateMu.Lock()
-	defer a.stateMu.Unlock()
-	a.state = state
-}
-
-func (a *adapter) getProxy(host string) (*rproxy.ReverseProxy, error) {
-	a.proxiesMu.Lock()
-	defer a.proxiesMu.Unlock()
+// checkHealth requests the ReadyPath from the app on the given port or socket
+// and returns an error unless it responds with the expected status (or any
+// non-error status by default)
+func (a *adapter) checkHealth(addr string) error {
+	if a.ReadyPath == "" {
+		return nil
+	}
 
-	if a.proxies[host] != nil {
-		return a.proxies[host], nil
+	client := &http.Client{
+		Timeout: a.ReadyTimeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialAddress(ctx, addr)
+			},
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
 	}
+	defer client.CloseIdleConnections()
 
-	url, err := url.Parse(a.Scheme + "://127.0.0.1:" + a.Port)
+	req, err := http.NewRequest("GET", addressURL(a.Scheme, addr)+a.ReadyPath, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	proxy, err := rproxy.New(url, host)
+	req.Host = a.Host
+
+	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
 
-	a.proxies[host] = proxy
+	if a.ReadyStatus != 0 && resp.StatusCode != a.ReadyStatus {
+		return errors.Format("%s returned %d, expected %d", a.ReadyPath, resp.StatusCode, a.ReadyStatus)
+	}
+	if a.ReadyStatus == 0 && resp.StatusCode >= 400 {
+		return errors.Format("%s returned %d", a.ReadyPath, resp.StatusCode)
+	}
 
-	return proxy, nil
+	return nil
 }
 
-func findAvailablePort() (string, error) {
-	l, err := net.Listen("tcp", ":0")
-	if err != nil {
-		return "", err
-	}
-	l.Close()
+// healthMonitor keeps checking the ReadyPath while the app is running and
+// marks it unhealthy after consecutive failures
+func (a *adapter) healthMonitor(cancel chan struct{}, addr string) {
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
 
-	_, port, err := net.SplitHostPort(l.Addr().String())
-	if err != nil {
-		return "", err
+	failures := 0
+
+	for {
+		select {
+		case <-cancel:
+			return
+		case <-ticker.C:
+			err := a.checkHealth(addr)
+			if err == nil {
+				failures = 0
+				if a.Status() == zadapter.StatusUnhealthy {
+					log.Println("[app]", a.Host, "health check passed, app is running")
+					a.Lock()
+					a.HealthError = ""
+					a.Unlock()
+					a.changeStateFrom(zadapter.StatusUnhealthy, zadapter.StatusRunning)
+				}
+				continue
+			}
+
+			failures++
+			log.Println("[app]", a.Host, "health check failed", failures, err)
+			if failures >= healthCheckFailures && a.Status() == zadapter.StatusRunning {
+				a.Lock()
+				a.HealthError = err.Error()
+				a.Unlock()
+				a.changeStateFrom(zadapter.StatusRunning, zadapter.StatusUnhealthy)
+			}
+		}
 	}
+}
 
-	return port, nil
+func (a *adapter) changeState(state zadapter.Status) {
+	a.stateMu.Lock()
+	defer a.stateMu.Unlock()
+	a.state = state
+}
+
+func (a *adapter) setReady(ready bool) {
+	a.stateMu.Lock()
+	defer a.stateMu.Unlock()
+	a.ready = ready
+}
+
+// isReady reports whether the ready pattern has matched, or there isn't one
+func (a *adapter) isReady() bool {
+	a.stateMu.Lock()
+	defer a.stateMu.Unlock()
+	return a.ready
+}
+
+// changeStateFrom changes the state only if the adapter is still in the given
+// state, so a stopping adapter isn't flipped back by a late health check
+func (a *adapter) changeStateFrom(from, to zadapter.Status) {
+	a.stateMu.Lock()
+	defer a.stateMu.Unlock()
+	if a.state == from {
+		a.state = to
+	}
 }
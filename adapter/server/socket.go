@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/vektra/errors"
+)
+
+// address is where the web process listens, the Unix socket path when the
+// app uses one or its port otherwise
+func (a *adapter) address() string {
+	if a.Socket != "" {
+		return a.Socket
+	}
+	return a.Port
+}
+
+// setAddress records where the web process listens
+func (a *adapter) setAddress(addr string) {
+	if isSocket(addr) {
+		a.Socket = addr
+		a.Port = ""
+		return
+	}
+	a.Socket = ""
+	a.Port = addr
+}
+
+// newSocket returns a path in the socket dir for the web process to listen
+// on, removing anything left there by an earlier run. A replacement is given
+// a path of its own so it can listen alongside the processes it replaces
+func (a *adapter) newSocket(replacement bool) (string, error) {
+	if err := os.MkdirAll(a.SocketDir, 0755); err != nil {
+		return "", errors.Context(err, "creating socket dir")
+	}
+
+	name := a.Host + ".sock"
+	if replacement {
+		a.replacements++
+		name = fmt.Sprintf("%s.%d.sock", a.Host, a.replacements)
+	}
+	path := filepath.Join(a.SocketDir, name)
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return "", errors.Context(err, "removing old socket")
+	}
+	return path, nil
+}
+
+// releaseAddress frees the address a web process listened on once it has
+// stopped
+func (a *adapter) releaseAddress(addr string) {
+	if isSocket(addr) {
+		os.Remove(addr)
+		return
+	}
+	ports.releasePort(a.Host, addr)
+}
+
+func isSocket(addr string) bool {
+	return filepath.IsAbs(addr)
+}
+
+// dialAddress connects to the web process at the given port or socket path
+func dialAddress(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	if isSocket(addr) {
+		return d.DialContext(ctx, "unix", addr)
+	}
+	return d.DialContext(ctx, "tcp", ":"+addr)
+}
+
+// addressURL is the base URL of the web process, the host is a placeholder
+// when it listens on a socket as the proxy connects to the socket instead
+func addressURL(scheme, addr string) string {
+	if isSocket(addr) {
+		return scheme + "://localhost"
+	}
+	return scheme + "://127.0.0.1:" + addr
+}
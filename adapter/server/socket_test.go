@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zap-sockets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := &adapter{Host: "moo.test", SocketDir: dir}
+
+	stale := filepath.Join(dir, "moo.test.sock")
+	if err := ioutil.WriteFile(stale, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := a.newSocket(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != stale {
+		t.Error("expected", stale, "got", path)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("expected the stale socket to be removed")
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	c, err := dialAddress(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	replacement, err := a.newSocket(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replacement == path || filepath.Dir(replacement) != dir {
+		t.Error("expected a replacement socket alongside", path, "got", replacement)
+	}
+	if next, _ := a.newSocket(true); next == replacement {
+		t.Error("expected each replacement to have its own socket, got", next)
+	}
+}
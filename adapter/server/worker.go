@@ -0,0 +1,221 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"os/exec"
+	"sync"
+	"time"
+
+	zadapter "github.com/moomerman/zap/adapter"
+	"github.com/moomerman/zap/logfile"
+)
+
+// Process is an extra process that runs alongside the web process, such as
+// a worker or asset watcher from a Procfile
+type Process struct {
+	Name    string
+	Command string
+}
+
+// worker status values
+const (
+	workerRunning    = "running"
+	workerRestarting = "restarting"
+	workerStopped    = "stopped"
+)
+
+// minWorkerBackoff and maxWorkerBackoff bound how long a worker waits before
+// it is restarted after exiting
+const (
+	minWorkerBackoff = 1 * time.Second
+	maxWorkerBackoff = 30 * time.Second
+)
+
+// worker supervises a single Procfile process, restarting it when it exits
+// until it is stopped
+type worker struct {
+	Name       string
+	Command    string
+	Pid        int
+	Status     string
+	Restarts   int
+	KilledPids []int
+
+	mu      sync.Mutex
+	adapter *adapter
+	cmd     *exec.Cmd
+	quit    chan struct{}
+	done    chan struct{}
+}
+
+func newWorker(a *adapter, process Process) *worker {
+	return &worker{
+		Name:    process.Name,
+		Command: process.Command,
+		adapter: a,
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// MarshalJSON locks the worker so its status can be read while it runs
+func (w *worker) MarshalJSON() ([]byte, error) {
+	return json.Marshal(w.status())
+}
+
+func (w *worker) status() zadapter.ProcessStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return zadapter.ProcessStatus{
+		Name:       w.Name,
+		Command:    w.Command,
+		Pid:        w.Pid,
+		Status:     w.Status,
+		Restarts:   w.Restarts,
+		KilledPids: w.KilledPids,
+	}
+}
+
+// run starts the process and restarts it with a backoff whenever it exits
+func (w *worker) run(logFile *logfile.File) {
+	defer close(w.done)
+	backoff := minWorkerBackoff
+
+	for {
+		started := time.Now()
+		if err := w.runOnce(logFile); err != nil {
+			log.Println("[app]", w.adapter.Host, w.Name, "ERROR", err)
+		}
+
+		select {
+		case <-w.quit:
+			return
+		default:
+		}
+
+		if time.Since(started) > maxWorkerBackoff {
+			backoff = minWorkerBackoff
+		}
+
+		log.Println("[app]", w.adapter.Host, w.Name, "exited, restarting in", backoff)
+		w.setStatus(workerRestarting)
+
+		select {
+		case <-w.quit:
+			return
+		case <-time.After(backoff):
+		}
+
+		w.mu.Lock()
+		w.Restarts++
+		w.mu.Unlock()
+
+		backoff *= 2
+		if backoff > maxWorkerBackoff {
+			backoff = maxWorkerBackoff
+		}
+	}
+}
+
+// runOnce starts the process and waits for its output to close
+func (w *worker) runOnce(logFile *logfile.File) error {
+	cmd := w.adapter.newCommand(w.Command, "")
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	select {
+	case <-w.quit:
+		w.mu.Unlock()
+		return nil
+	default:
+	}
+	if err := cmd.Start(); err != nil {
+		w.mu.Unlock()
+		return err
+	}
+	w.cmd = cmd
+	w.Pid = cmd.Process.Pid
+	w.Status = workerRunning
+	w.mu.Unlock()
+
+	w.adapter.limit(cmd.Process.Pid)
+
+	log.Println("[app]", w.adapter.Host, w.Name, "started", w.Command)
+
+	var wg sync.WaitGroup
+	read := func(stream string, r io.Reader) {
+		defer wg.Done()
+		br := bufio.NewReader(r)
+		for {
+			text, err := br.ReadString('\n')
+			if text != "" {
+				w.adapter.appendLog(logFile, cmd.Process.Pid, zadapter.LogLine{Time: time.Now(), Process: w.Name, Stream: stream, Text: text})
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	wg.Add(2)
+	go read(zadapter.StreamStdout, stdout)
+	go read(zadapter.StreamStderr, stderr)
+	wg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	select {
+	case <-w.quit:
+		// Stop is waiting on the process
+		return nil
+	default:
+	}
+
+	w.cmd = nil
+	w.Pid = 0
+	return cmd.Wait()
+}
+
+// Stop stops the process and waits for the supervisor to exit
+func (w *worker) Stop() {
+	w.mu.Lock()
+	close(w.quit)
+	cmd := w.cmd
+	w.mu.Unlock()
+
+	if cmd != nil {
+		killed, err := stopGroup(cmd.Process.Pid, func() { cmd.Wait() }, w.adapter.StopTimeout)
+		if err != nil {
+			log.Println("[app]", w.adapter.Host, w.Name, "error trying to stop", err)
+		}
+		w.mu.Lock()
+		w.KilledPids = killed
+		w.mu.Unlock()
+	}
+
+	<-w.done
+
+	w.mu.Lock()
+	w.cmd = nil
+	w.Pid = 0
+	w.Status = workerStopped
+	w.mu.Unlock()
+}
+
+func (w *worker) setStatus(status string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.Status = status
+}
@@ -83,3 +83,6 @@ func (d *adapter) Command() *exec.Cmd { return nil }
 
 // WriteLog doesn't do anything
 func (d *adapter) WriteLog(w io.Writer) {}
+
+// LogLines doesn't do anything
+func (d *adapter) LogLines() []zadapter.LogLine { return nil }
@@ -107,12 +107,17 @@ type Cache struct {
 
 // NewCache holds the dynamically generated host certificates
 func NewCache() (*Cache, error) {
+	return NewCacheWithSize(1024)
+}
+
+// NewCacheWithSize holds up to size dynamically generated host certificates
+func NewCacheWithSize(size int) (*Cache, error) {
 	err := loadCertLegacy()
 	if err != nil {
 		return nil, errors.Context(err, "couldn't load root certificate")
 	}
 
-	cache, err := lru.NewARC(1024)
+	cache, err := lru.NewARC(size)
 	if err != nil {
 		return nil, errors.Context(err, "couldn't create a new cache")
 	}
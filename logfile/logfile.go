@@ -0,0 +1,178 @@
+package logfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vektra/errors"
+)
+
+// TimeFormat is the format of the timestamp at the start of every line
+const TimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// File is a log file that is rotated once it grows beyond MaxSize, keeping
+// MaxFiles previous generations alongside it as path.1, path.2 etc.
+type File struct {
+	Path     string
+	MaxSize  int64
+	MaxFiles int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Open opens (or creates) the log file at the given path for appending
+func Open(path string, maxSize int64, maxFiles int) (*File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, errors.Context(err, "creating log directory")
+	}
+
+	f := &File{
+		Path:     path,
+		MaxSize:  maxSize,
+		MaxFiles: maxFiles,
+	}
+
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// WriteLine writes the line to the file prefixed with the given time
+func (f *File) WriteLine(t time.Time, line string) error {
+	if !strings.HasSuffix(line, "\n") {
+		line += "\n"
+	}
+	_, err := f.Write([]byte(t.Format(TimeFormat) + " " + line))
+	return err
+}
+
+// Write implements the io.Writer interface, rotating the file first if the
+// write would take it over MaxSize
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if f.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.MaxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close closes the file
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *File) open() error {
+	file, err := os.OpenFile(f.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return errors.Context(err, "opening log file")
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return errors.Context(err, "reading log file")
+	}
+
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+func (f *File) rotate() error {
+	f.file.Close()
+
+	os.Remove(Generation(f.Path, f.MaxFiles))
+	for n := f.MaxFiles - 1; n >= 0; n-- {
+		os.Rename(Generation(f.Path, n), Generation(f.Path, n+1))
+	}
+	if f.MaxFiles == 0 {
+		os.Remove(f.Path)
+	}
+
+	return f.open()
+}
+
+// Generation returns the path of the nth generation of the log file where 0
+// is the current file
+func Generation(path string, n int) string {
+	if n == 0 {
+		return path
+	}
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// WriteGeneration copies the nth generation of the log file to the writer
+func WriteGeneration(w io.Writer, path string, n int) error {
+	file, err := os.Open(Generation(path, n))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(w, file)
+	return err
+}
+
+// WriteSince copies every line written at or after the given time to the
+// writer, oldest generation first
+func WriteSince(w io.Writer, path string, maxFiles int, since time.Time) error {
+	for n := maxFiles; n >= 0; n-- {
+		file, err := os.Open(Generation(path, n))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			t, err := time.Parse(TimeFormat, strings.SplitN(line, " ", 2)[0])
+			if err != nil || t.Before(since) {
+				continue
+			}
+			if _, err := io.WriteString(w, line+"\n"); err != nil {
+				file.Close()
+				return err
+			}
+		}
+		file.Close()
+
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package logfile
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	f, err := Open(path, 100, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now().Truncate(time.Second)
+	for i := 0; i < 10; i++ {
+		if err := f.WriteLine(start.Add(time.Duration(i)*time.Second), "some log output"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Close()
+
+	for n := 0; n <= 2; n++ {
+		if _, err := os.Stat(Generation(path, n)); err != nil {
+			t.Error("expected generation", n, "to exist", err)
+		}
+	}
+	if _, err := os.Stat(Generation(path, 3)); !os.IsNotExist(err) {
+		t.Error("expected generation 3 to have been removed")
+	}
+
+	buf := bytes.NewBufferString("")
+	if err := WriteSince(buf, path, 2, start.Add(8*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 2 {
+		t.Error("expected 2 lines since, got", lines)
+	}
+}
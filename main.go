@@ -14,6 +14,7 @@ import (
 )
 
 var (
+	fConfig     = flag.String("config", zap.ConfigPath, "path to the zapd config file")
 	fInstall    = flag.Bool("install", false, "Install the server")
 	fUninstall  = flag.Bool("uninstall", false, "Uninstall the server")
 	fHTTP       = flag.String("http", "127.0.0.1:80", "address to listen on for HTTP requests")
@@ -29,8 +30,27 @@ func init() {
 func main() {
 	flag.Parse()
 
+	config, err := zap.LoadConfig(*fConfig)
+	if err != nil {
+		log.Fatal("[zap] unable to load config ", err)
+	}
+
+	// flags given on the command line take precedence over the config file
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "http":
+			config.HTTP = *fHTTP
+		case "https":
+			config.HTTPS = *fHTTPS
+		case "dns":
+			config.DNS = *fDNS
+		case "domains":
+			config.Domains = strings.Split(*fDNSDomains, ":")
+		}
+	})
+
 	if *fInstall {
-		if err := zap.Install(*fHTTP, *fHTTPS, *fDNS); err != nil {
+		if err := zap.Install(config.HTTP, config.HTTPS, config.DNS); err != nil {
 			log.Fatal("[zap] unable to install zap", err)
 		}
 		return
@@ -44,18 +64,19 @@ func main() {
 	}
 
 	responder := &dns.Responder{
-		Address: *fDNS,
-		Domains: strings.Split(*fDNSDomains, ":"),
+		Address: config.DNS,
+		Domains: config.Domains,
 	}
 
 	server := &zap.Server{
-		HTTPAddr:  *fHTTP,
-		HTTPSAddr: *fHTTPS,
+		HTTPAddr:  config.HTTP,
+		HTTPSAddr: config.HTTPS,
+		Config:    config,
 	}
 
 	go func() {
-		ch := make(chan os.Signal)
-		signal.Notify(ch, os.Interrupt, os.Kill, syscall.SIGTERM)
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
 
 		log.Printf("[zap] caught signal '%v' shutting down\n", <-ch)
 		responder.Stop()
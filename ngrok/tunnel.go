@@ -26,10 +26,18 @@ type Tunnel struct {
 	stdout io.Reader
 }
 
+// DefaultRegion is the ngrok region used by StartTunnel
+const DefaultRegion = "eu"
+
 // StartTunnel starts a new ngrok tunnel for the given host and port
 func StartTunnel(host string, port int) (*Tunnel, error) {
+	return StartTunnelInRegion(host, port, DefaultRegion)
+}
+
+// StartTunnelInRegion starts a new ngrok tunnel in the given region
+func StartTunnelInRegion(host string, port int, region string) (*Tunnel, error) {
 
-	ngrok, err := startTunnel(host, port)
+	ngrok, err := startTunnel(host, port, region)
 	if err != nil {
 		return nil, err
 	}
@@ -140,9 +148,8 @@ func (n *Tunnel) getAdminURL() string {
 	return n.AdminURL
 }
 
-func startTunnel(host string, port int) (*Tunnel, error) {
-	// TODO: make the options configurable
-	command := fmt.Sprintf("exec ngrok http --region=eu --host-header=%s --bind-tls=true --log-format=logfmt --log=stdout --log-level=debug %d", host, port)
+func startTunnel(host string, port int, region string) (*Tunnel, error) {
+	command := fmt.Sprintf("exec ngrok http --region=%s --host-header=%s --bind-tls=true --log-format=logfmt --log=stdout --log-level=debug %d", region, host, port)
 	shell := os.Getenv("SHELL")
 	cmd := exec.Command(shell, "-l", "-c", command)
 	cmd.Env = os.Environ()
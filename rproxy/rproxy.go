@@ -27,6 +27,20 @@ func New(target *url.URL, hostname string) (*ReverseProxy, error) {
 
 // NewWithTrustedCertificates returns a new ReverseProxy
 func NewWithTrustedCertificates(target *url.URL, hostname string, certs []*tls.Certificate) (*ReverseProxy, error) {
+	return newReverseProxy(target, hostname, certs, func(network, addr string) (string, string) {
+		return network, addr
+	})
+}
+
+// NewWithSocket returns a new ReverseProxy that connects to the Unix socket at
+// path whatever the host of the target
+func NewWithSocket(target *url.URL, hostname, path string) (*ReverseProxy, error) {
+	return newReverseProxy(target, hostname, nil, func(network, addr string) (string, string) {
+		return "unix", path
+	})
+}
+
+func newReverseProxy(target *url.URL, hostname string, certs []*tls.Certificate, dialAddr func(network, addr string) (string, string)) (*ReverseProxy, error) {
 	targetQuery := target.RawQuery
 
 	director := func(req *http.Request) {
@@ -69,6 +83,7 @@ func NewWithTrustedCertificates(target *url.URL, hostname string, certs []*tls.C
 		transport: &http.Transport{
 			Proxy: http.ProxyFromEnvironment,
 			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				network, addr = dialAddr(network, addr)
 				conn, err := (&net.Dialer{
 					Timeout:   30 * time.Second,
 					KeepAlive: 60 * time.Second,
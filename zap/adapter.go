@@ -2,28 +2,156 @@ package zap
 
 import (
 	"log"
+	"regexp"
+	"strings"
 
 	"github.com/moomerman/zap/adapter"
 	"github.com/moomerman/zap/adapter/server"
 	"github.com/moomerman/zap/adapter/static"
+	"github.com/puma/puma-dev/homedir"
+	"github.com/vektra/errors"
 )
 
-// GetAdapter returns the corresponding adapter for the given config
-func GetAdapter(scheme, host, port, dir, command string) (adapter.Adapter, error) {
+// GetAdapter returns the corresponding adapter for the given config, new log
+// lines from the adapter are passed to onLog
+func GetAdapter(config *AppConfig, onLog func(line adapter.LogLine)) (adapter.Adapter, error) {
+	serverConfig, err := getValidServerConfig(config, onLog)
+	if err != nil {
+		return nil, err
+	}
+
+	if serverConfig != nil {
+		return server.New(serverConfig), nil
+	}
+
+	log.Println("[app]", config.Host, "using the static adapter")
+	return static.New(config.Dir)
+}
+
+// getValidServerConfig checks the parts of the app config that aren't
+// checked when it's loaded and returns the server adapter config
+func getValidServerConfig(config *AppConfig, onLog func(line adapter.LogLine)) (*server.Config, error) {
+	if err := validateWatch(config.Watch); err != nil {
+		return nil, err
+	}
+	if err := validateRoutes(config.Routes); err != nil {
+		return nil, err
+	}
+	return getServerConfig(config, onLog)
+}
+
+// getServerConfig returns the server adapter config for an app that runs a
+// command, or nil if it doesn't
+func getServerConfig(config *AppConfig, onLog func(line adapter.LogLine)) (*server.Config, error) {
+	command := config.Command
+	processes := []server.Process{}
+
+	if path := config.procfilePath(); path != "" {
+		procfile, err := readProcfile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, process := range procfile {
+			if process.Name == "web" {
+				// command replaces the Procfile web process rather than
+				// running alongside it on the same port
+				if command == "" {
+					command = process.Command
+				} else {
+					log.Println("[app]", config.Host, "using command instead of the web process in", path)
+				}
+				continue
+			}
+			processes = append(processes, process)
+		}
+
+		if command == "" {
+			return nil, errors.Format("no web process in %s", path)
+		}
+		log.Println("[app]", config.Host, "using", path, "with", len(processes), "other processes")
+	}
 
 	if command != "" {
-		config := &server.Config{
-			Name:         "Server",
-			Scheme:       scheme,
-			Host:         host,
-			Dir:          dir,
-			EnvPortName:  port,
-			ShellCommand: "exec " + command + " # %s %s",
+		if config.Socket && config.SocketActivation {
+			return nil, errors.New("socket and socket_activation can't be used together")
+		}
+
+		switch config.Run {
+		case "", server.RunLogin, server.RunShell, server.RunExec:
+		default:
+			return nil, errors.Format("invalid run mode %q", config.Run)
+		}
+
+		limits, err := config.limits()
+		if err != nil {
+			return nil, err
+		}
+
+		var readyPattern *regexp.Regexp
+		if config.ReadyPattern != "" {
+			pattern, err := regexp.Compile(config.ReadyPattern)
+			if err != nil {
+				return nil, errors.Context(err, "invalid ready_pattern")
+			}
+			readyPattern = pattern
+		}
+
+		if config.ReadyPath != "" && !strings.HasPrefix(config.ReadyPath, "/") {
+			return nil, errors.Format("ready_path %q must start with /", config.ReadyPath)
+		}
+
+		restartPatterns := []server.RestartPattern{}
+		for _, restartOn := range config.RestartOn {
+			pattern, err := regexp.Compile(restartOn.Pattern)
+			if err != nil {
+				return nil, errors.Context(err, "invalid restart_on pattern")
+			}
+			if restartOn.Stream != "" && restartOn.Stream != adapter.StreamStdout && restartOn.Stream != adapter.StreamStderr {
+				return nil, errors.Format("invalid restart_on stream %q", restartOn.Stream)
+			}
+			restartPatterns = append(restartPatterns, server.RestartPattern{Pattern: pattern, Stream: restartOn.Stream})
+		}
+
+		spoolDir := ""
+		if globalConfig.Orphans == orphansAdopt {
+			spoolDir = homedir.MustExpand(spoolPath)
+		}
+
+		socketDir := ""
+		if config.Socket {
+			socketDir = homedir.MustExpand(socketPath)
 		}
 
-		return server.New(config), nil
+		return &server.Config{
+			Name:             "Server",
+			Scheme:           config.Scheme,
+			Host:             config.Host,
+			Dir:              config.Dir,
+			EnvPortName:      config.Port,
+			ShellCommand:     "exec " + command + " # %s %s",
+			RunMode:          config.Run,
+			Shell:            config.Shell,
+			ShellArgs:        config.ShellArgs,
+			RestartPatterns:  restartPatterns,
+			BootTimeout:      config.bootTimeout(),
+			StopTimeout:      config.stopTimeout(),
+			LogFile:          config.logFile(),
+			LogMaxSize:       globalConfig.LogMaxSize,
+			LogMaxFiles:      globalConfig.LogMaxFiles,
+			OnLog:            onLog,
+			ReadyPath:        config.ReadyPath,
+			ReadyStatus:      config.ReadyStatus,
+			ReadyTimeout:     config.ReadyTimeout,
+			ReadyPattern:     readyPattern,
+			Processes:        processes,
+			Env:              config.Env,
+			Limits:           limits,
+			SpoolDir:         spoolDir,
+			SocketActivation: config.SocketActivation,
+			SocketDir:        socketDir,
+		}, nil
 	}
 
-	log.Println("[app]", host, "using the static adapter")
-	return static.New(dir)
+	return nil, nil
 }
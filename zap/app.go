@@ -2,9 +2,11 @@ package zap
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"log"
 	"net/http"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
@@ -20,7 +22,9 @@ var apps map[string]*app
 
 // app holds the state of a running Application
 type app struct {
-	Config *AppConfig
+	configMu    sync.RWMutex
+	Config      *AppConfig
+	ConfigError string `json:",omitempty"`
 
 	lastUsedMu sync.Mutex
 	LastUsed   time.Time
@@ -30,6 +34,132 @@ type app struct {
 
 	Started time.Time
 	Ngrok   *ngrok.Tunnel
+
+	LastStopReason string `json:",omitempty"`
+
+	logs logStream
+
+	ConfigChanged time.Time `json:",omitempty"`
+	ConfigChanges []string  `json:",omitempty"`
+
+	crashMu        sync.Mutex
+	Exits          []time.Time `json:",omitempty"`
+	CrashLoopUntil time.Time
+	backoff        time.Duration
+	exitedAdapter  adapter.Adapter
+
+	dependsMu       sync.Mutex
+	Waiting         bool   `json:",omitempty"`
+	WaitingFor      string `json:",omitempty"`
+	WaitingStatus   string `json:",omitempty"`
+	DependencyError string `json:",omitempty"`
+
+	holdMu sync.Mutex
+	held   int
+
+	monitoring bool
+	watcher    *fileWatcher
+
+	buildMu    sync.Mutex
+	BuildError string `json:",omitempty"`
+
+	routesMu sync.Mutex
+	routes   []*route
+}
+
+// appSnapshot is a copy of the exported app state taken under the locks that
+// guard it
+type appSnapshot struct {
+	Config      *AppConfig
+	ConfigError string `json:",omitempty"`
+
+	LastUsed time.Time
+
+	Adapter adapter.Adapter
+
+	Started time.Time
+	Ngrok   *ngrok.Tunnel
+
+	LastStopReason string `json:",omitempty"`
+
+	ConfigChanged time.Time `json:",omitempty"`
+	ConfigChanges []string  `json:",omitempty"`
+
+	Exits          []time.Time `json:",omitempty"`
+	CrashLoopUntil time.Time
+
+	Waiting         bool   `json:",omitempty"`
+	WaitingFor      string `json:",omitempty"`
+	WaitingStatus   string `json:",omitempty"`
+	DependencyError string `json:",omitempty"`
+
+	BuildError string `json:",omitempty"`
+}
+
+// snapshot copies the exported state of the app so it can be read while the
+// app runs
+func (a *app) snapshot() appSnapshot {
+	s := appSnapshot{Started: a.Started}
+
+	a.configMu.RLock()
+	s.Config = a.Config
+	s.ConfigError = a.ConfigError
+	s.ConfigChanged = a.ConfigChanged
+	s.ConfigChanges = a.ConfigChanges
+	a.configMu.RUnlock()
+
+	a.lastUsedMu.Lock()
+	s.LastUsed = a.LastUsed
+	a.lastUsedMu.Unlock()
+
+	a.adapterMu.Lock()
+	s.Adapter = a.Adapter
+	s.Ngrok = a.Ngrok
+	s.LastStopReason = a.LastStopReason
+	a.adapterMu.Unlock()
+
+	a.crashMu.Lock()
+	s.Exits = append([]time.Time{}, a.Exits...)
+	s.CrashLoopUntil = a.CrashLoopUntil
+	a.crashMu.Unlock()
+
+	a.dependsMu.Lock()
+	s.Waiting = a.Waiting
+	s.WaitingFor = a.WaitingFor
+	s.WaitingStatus = a.WaitingStatus
+	s.DependencyError = a.DependencyError
+	a.dependsMu.Unlock()
+
+	a.buildMu.Lock()
+	s.BuildError = a.BuildError
+	a.buildMu.Unlock()
+
+	return s
+}
+
+// MarshalJSON marshals a snapshot of the app so the API can read it while
+// the app runs
+func (a *app) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.snapshot())
+}
+
+// config returns the app config, Reload replaces it while requests are being
+// served
+func (a *app) config() *AppConfig {
+	a.configMu.RLock()
+	defer a.configMu.RUnlock()
+	return a.Config
+}
+
+// setConfigError records why the config couldn't be read, the app keeps
+// running with the config it has
+func (a *app) setConfigError(err error) {
+	a.configMu.Lock()
+	defer a.configMu.Unlock()
+	a.ConfigError = ""
+	if err != nil {
+		a.ConfigError = err.Error()
+	}
 }
 
 // newApp creates a new App with the given configuration
@@ -43,23 +173,28 @@ func newApp(config *AppConfig) (*app, error) {
 		return nil, err
 	}
 
+	routes, err := newRoutes(config)
+	if err != nil {
+		return nil, errors.Context(err, "could not create routes")
+	}
+	app.routes = routes
+
 	return app, nil
 }
 
+// newAdapter creates the adapter for the current config, the caller must
+// hold adapterMu or be the only reference to the app
 func (a *app) newAdapter() error {
-	a.adapterMu.Lock()
-	a.adapterMu.Unlock()
-
 	var adpt adapter.Adapter
 	var err error
 
-	if a.Config.Dir != "" {
-		adpt, err = GetAdapter(a.Config.Scheme, a.Config.Host, a.Config.Port, a.Config.Dir, a.Config.Command)
+	if a.config().Dir != "" {
+		adpt, err = GetAdapter(a.config(), a.logs.Publish)
 		if err != nil {
 			return errors.Context(err, "could not determine adapter")
 		}
 	} else {
-		adpt, err = proxy.New(a.Config.Host, a.Config.Proxy)
+		adpt, err = proxy.New(a.config().Host, a.config().Proxy)
 		if err != nil {
 			return errors.Context(err, "unable to create proxy adapter")
 		}
@@ -74,46 +209,187 @@ func (a *app) Start() error {
 	a.adapterMu.Lock()
 	defer a.adapterMu.Unlock()
 
+	return a.start()
+}
+
+func (a *app) start() error {
 	err := a.Adapter.Start()
 	if err != nil {
 		return err
 	}
 
 	a.touch()
+	a.startRoutes()
 
-	go a.idleMonitor()
+	if !a.monitoring {
+		a.monitoring = true
+		go a.idleMonitor()
+	}
+
+	if a.watcher == nil && len(a.config().Watch.Include) > 0 {
+		a.watcher = newFileWatcher(a)
+		go a.watcher.Watch()
+	}
 	return nil
 }
 
 // Stop stops an application handler and removes the app
 func (a *app) Stop(reason string, e error) error {
 	appsMu.Lock()
-	delete(apps, a.Config.Key)
+	// another app may already be registered under the key after a reload
+	if apps[a.config().Key] == a {
+		delete(apps, a.config().Key)
+	}
 	appsMu.Unlock()
 
 	a.adapterMu.Lock()
 	defer a.adapterMu.Unlock()
 
-	log.Println("[app]", a.Config.Host, "stopping", reason, e)
+	if a.watcher != nil {
+		a.watcher.Stop()
+		a.watcher = nil
+	}
+
+	log.Println("[app]", a.config().Host, "stopping", reason, e)
+	a.stopRoutes(errors.Context(e, reason))
 	return a.Adapter.Stop(errors.Context(e, reason))
 }
 
-// Restart restarts an application adapter
+// Restart restarts an application adapter, without downtime if the app is
+// set up for it and running
 func (a *app) RestartAdapter() error {
+	if replaced, err := a.replaceAdapter(); replaced {
+		return err
+	}
+
 	a.adapterMu.Lock()
 	defer a.adapterMu.Unlock()
 
+	return a.restartAdapter()
+}
+
+// replaceAdapter boots a new app process alongside the running one when the
+// app has zero_downtime set, returning false if the adapter has to be
+// restarted instead
+func (a *app) replaceAdapter() (bool, error) {
+	if !a.config().ZeroDowntime {
+		return false, nil
+	}
+	return a.replace()
+}
+
+// replace boots a new app process alongside the running one, returning false
+// if the adapter can't do that and has to be restarted instead
+func (a *app) replace() (bool, error) {
+	a.adapterMu.Lock()
+	adpt := a.Adapter
+	a.adapterMu.Unlock()
+
+	r, ok := adpt.(interface {
+		Replace() (bool, error)
+	})
+	if !ok {
+		return false, nil
+	}
+	return r.Replace()
+}
+
+func (a *app) restartAdapter() error {
+	if reason := a.stopReason(); reason != "" {
+		a.LastStopReason = reason
+	}
 	if err := a.Adapter.Stop(errors.New("requested restart")); err != nil {
-		log.Println("[app]", a.Config.Host, "error stopping adapter on restart", err)
+		log.Println("[app]", a.config().Host, "error stopping adapter on restart", err)
 	}
 	if err := a.newAdapter(); err != nil {
 		return err
 	}
-	return a.Start()
+	return a.start()
+}
+
+// Reload applies a changed config to the app, restarting the adapter or
+// replacing the app entirely if the config now points at a different backend
+func (a *app) Reload(config *AppConfig) error {
+	changes := diffAppConfig(a.config(), config)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	for _, change := range changes {
+		log.Println("[app]", a.config().Host, "config changed", change)
+	}
+
+	if config.Key != a.config().Key {
+		a.Stop("config changed", nil)
+
+		// an app may already be running for the new key, it serves the host
+		// from now on rather than being left running without an entry
+		appsMu.Lock()
+		_, exists := apps[config.Key]
+		appsMu.Unlock()
+		if exists {
+			return nil
+		}
+
+		app, err := newApp(config)
+		if err != nil {
+			return errors.Context(err, "app failed to create")
+		}
+		app.configChanged(changes)
+
+		if err := app.Start(); err != nil {
+			return errors.Context(err, "app failed to start")
+		}
+
+		appsMu.Lock()
+		existing, exists := apps[config.Key]
+		if !exists {
+			apps[config.Key] = app
+		}
+		appsMu.Unlock()
+		if exists && existing != app {
+			return app.Stop("app already running", nil)
+		}
+		return nil
+	}
+
+	a.adapterMu.Lock()
+	defer a.adapterMu.Unlock()
+
+	// the new config needs a new adapter so this is never a zero downtime
+	// restart
+	// the watcher compares scans of the dir it was started with, a new one
+	// is started with the adapter
+	if a.watcher != nil && (config.Dir != a.config().Dir || !reflect.DeepEqual(config.Watch, a.config().Watch)) {
+		a.watcher.Stop()
+		a.watcher = nil
+	}
+
+	a.configMu.Lock()
+	a.Config = config
+	a.configMu.Unlock()
+	a.configChanged(changes)
+
+	a.stopRoutes(errors.New("config changed"))
+	routes, err := newRoutes(config)
+	if err != nil {
+		return errors.Context(err, "could not create routes")
+	}
+	a.routesMu.Lock()
+	a.routes = routes
+	a.routesMu.Unlock()
+
+	return a.restartAdapter()
 }
 
 // Status returns the status of the application
 func (a *app) Status() string {
+	if waiting, failed := a.waiting(); waiting || failed {
+		return statusWaiting
+	}
+	if a.inCrashLoop() {
+		return statusCrashLoop
+	}
 	return string(a.Adapter.Status())
 }
 
@@ -127,6 +403,11 @@ func (a *app) WriteLog(w io.Writer) {
 	a.Adapter.WriteLog(w)
 }
 
+// LogLines returns the buffered log lines tagged with their stream
+func (a *app) LogLines() []adapter.LogLine {
+	return a.Adapter.LogLines()
+}
+
 // LogTail returns the last X lines of the log file
 func (a *app) LogTail() string {
 	buf := bytes.NewBufferString("")
@@ -134,21 +415,73 @@ func (a *app) LogTail() string {
 	return buf.String()
 }
 
+// Processes returns the status of the Procfile processes running alongside
+// the web process, if the adapter has any
+func (a *app) Processes() []adapter.ProcessStatus {
+	if p, ok := a.Adapter.(interface {
+		ProcessStatus() []adapter.ProcessStatus
+	}); ok {
+		return p.ProcessStatus()
+	}
+	return nil
+}
+
+// Warnings returns problems the adapter found with the app setup, such as
+// env files that couldn't be parsed
+func (a *app) Warnings() []string {
+	if w, ok := a.Adapter.(interface {
+		Warnings() []string
+	}); ok {
+		return w.Warnings()
+	}
+	return nil
+}
+
+// StopReason returns why the app was last stopped
+func (a *app) StopReason() string {
+	if reason := a.stopReason(); reason != "" {
+		return reason
+	}
+	return a.LastStopReason
+}
+
+// stopReason returns why the adapter stopped, if it has
+func (a *app) stopReason() string {
+	if a.Adapter.Status() != adapter.StatusStopped {
+		return ""
+	}
+	if r, ok := a.Adapter.(interface {
+		Reason() string
+	}); ok {
+		return r.Reason()
+	}
+	return ""
+}
+
 func (a *app) StartNgrok(host string, port int) error {
 	// TODO: check if another ngrok instance exists
 	// if so, stop it and cleanup
-	ngrok, err := ngrok.StartTunnel(host, port)
+	ngrok, err := ngrok.StartTunnelInRegion(host, port, globalConfig.NgrokRegion)
 	if err != nil {
 		return err
 	}
 
+	a.adapterMu.Lock()
 	a.Ngrok = ngrok
+	a.adapterMu.Unlock()
 
 	// TODO: add the symbolic link
 
 	return nil
 }
 
+func (a *app) configChanged(changes []string) {
+	a.configMu.Lock()
+	defer a.configMu.Unlock()
+	a.ConfigChanged = time.Now()
+	a.ConfigChanges = changes
+}
+
 func (a *app) touch() {
 	a.lastUsedMu.Lock()
 	defer a.lastUsedMu.Unlock()
@@ -156,7 +489,7 @@ func (a *app) touch() {
 }
 
 func (a *app) idleMonitor() {
-	log.Println("[app]", a.Config.Host, "starting idle monitor")
+	log.Println("[app]", a.config().Host, "starting idle monitor")
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
@@ -164,12 +497,15 @@ func (a *app) idleMonitor() {
 		select {
 		case <-ticker.C:
 			if a.idle() {
-				log.Println("[app]", a.Config.Host, "app is idle")
+				log.Println("[app]", a.config().Host, "app is idle")
 				a.Stop("app is idle", nil)
 				return
 			}
 			if a.Adapter.Status() == adapter.StatusStopped {
-				log.Println("[app]", a.Config.Host, "app is stopped, stopping idle monitor")
+				log.Println("[app]", a.config().Host, "app is stopped, stopping idle monitor")
+				a.adapterMu.Lock()
+				a.monitoring = false
+				a.adapterMu.Unlock()
 				return
 			}
 		}
@@ -177,8 +513,12 @@ func (a *app) idleMonitor() {
 }
 
 func (a *app) idle() bool {
+	if a.config().AlwaysOn {
+		return false
+	}
+
 	diff := time.Since(a.LastUsed)
-	if diff > 60*60*time.Second {
+	if diff > a.config().idleTimeout() {
 		return true
 	}
 
@@ -203,7 +543,21 @@ func findAppForHost(host string) (*app, error) {
 	app := apps[config.Key]
 
 	if app != nil {
-		if app.Status() == "stopped" {
+		if waiting, failed := app.waiting(); waiting {
+			return app, nil
+		} else if failed {
+			app.startAfterDependencies()
+			return app, nil
+		}
+
+		if app.Adapter.Status() == adapter.StatusStopped {
+			if app.crashed() {
+				return app, nil
+			}
+			if len(app.config().DependsOn) > 0 {
+				app.startAfterDependencies()
+				return app, nil
+			}
 			if err := app.RestartAdapter(); err != nil {
 				return nil, errors.Context(err, "app failed to restart")
 			}
@@ -213,12 +567,23 @@ func findAppForHost(host string) (*app, error) {
 
 	log.Println("[app]", host, config.Key, "creating app")
 
+	if err := checkDependencies(config, []string{host}); err != nil {
+		log.Println("[app]", host, config.Key, "invalid dependencies", err)
+		return nil, err
+	}
+
 	app, err = newApp(config)
 	if err != nil {
 		log.Println("[app]", host, config.Key, "error creating app", err)
 		return nil, errors.Context(err, "app failed to create")
 	}
 
+	if len(config.DependsOn) > 0 {
+		apps[config.Key] = app
+		app.startAfterDependencies()
+		return app, nil
+	}
+
 	if err := app.Start(); err != nil {
 		log.Println("[app]", host, config.Key, "error starting app", err)
 		return nil, errors.Context(err, "app failed to start")
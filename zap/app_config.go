@@ -1,9 +1,13 @@
 package zap
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
+	"time"
 
 	"github.com/puma/puma-dev/homedir"
 	"gopkg.in/yaml.v2"
@@ -11,6 +15,9 @@ import (
 
 const appsPath = "~/.zap"
 
+// socketPath is where apps with socket set listen
+const socketPath = appsPath + "/sockets"
+
 // AppConfig holds the configuration for a given host host
 type AppConfig struct {
 	Scheme  string
@@ -21,6 +28,116 @@ type AppConfig struct {
 	Command string `json:",omitempty"`
 	Proxy   string `json:",omitempty"`
 	Key     string
+
+	Procfile  string   `yaml:"procfile" json:",omitempty"`
+	DependsOn []string `yaml:"depends_on" json:",omitempty"`
+
+	Env map[string]string `yaml:"env" json:",omitempty"`
+
+	Run       string   `yaml:"run" json:",omitempty"`
+	Shell     string   `yaml:"shell" json:",omitempty"`
+	ShellArgs []string `yaml:"shell_args" json:",omitempty"`
+
+	MaxMemory    string `yaml:"max_memory" json:",omitempty"`
+	MaxOpenFiles int    `yaml:"max_open_files" json:",omitempty"`
+	Nice         int    `yaml:"nice" json:",omitempty"`
+	CPUQuota     string `yaml:"cpu_quota" json:",omitempty"`
+
+	IdleTimeout      time.Duration `yaml:"idle_timeout" json:",omitempty"`
+	BootTimeout      time.Duration `yaml:"boot_timeout" json:",omitempty"`
+	StopTimeout      time.Duration `yaml:"stop_timeout" json:",omitempty"`
+	AlwaysOn         bool          `yaml:"always_on" json:",omitempty"`
+	ZeroDowntime     bool          `yaml:"zero_downtime" json:",omitempty"`
+	SocketActivation bool          `yaml:"socket_activation" json:",omitempty"`
+	Socket           bool          `yaml:"socket" json:",omitempty"`
+
+	HoldRequests bool          `yaml:"hold_requests" json:",omitempty"`
+	HoldTimeout  time.Duration `yaml:"hold_timeout" json:",omitempty"`
+	HoldQueue    int           `yaml:"hold_queue" json:",omitempty"`
+
+	ReadyPath    string        `yaml:"ready_path" json:",omitempty"`
+	ReadyStatus  int           `yaml:"ready_status" json:",omitempty"`
+	ReadyTimeout time.Duration `yaml:"ready_timeout" json:",omitempty"`
+	ReadyPattern string        `yaml:"ready_pattern" json:",omitempty"`
+
+	RestartOn []RestartPattern `yaml:"restart_on" json:",omitempty"`
+
+	Watch WatchConfig `yaml:"watch"`
+
+	Routes []RouteConfig `yaml:"routes" json:",omitempty"`
+}
+
+// RestartPattern is a restart_on entry, either just a pattern or a pattern
+// and the stream (stdout or stderr) it applies to
+type RestartPattern struct {
+	Pattern string
+	Stream  string `json:",omitempty"`
+}
+
+// UnmarshalYAML accepts either a plain pattern or a pattern and stream
+func (p *RestartPattern) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&p.Pattern); err == nil {
+		return nil
+	}
+
+	type plain RestartPattern
+	return unmarshal((*plain)(p))
+}
+
+// WatchConfig is a watch entry, the globs of the files under the app dir
+// that restart the app when they change and a build command to run first.
+// A plain list of globs is taken as the include list
+type WatchConfig struct {
+	Include  []string      `yaml:"include" json:",omitempty"`
+	Exclude  []string      `yaml:"exclude" json:",omitempty"`
+	Build    string        `yaml:"build" json:",omitempty"`
+	Interval time.Duration `yaml:"interval" json:",omitempty"`
+	Debounce time.Duration `yaml:"debounce" json:",omitempty"`
+}
+
+// UnmarshalYAML accepts either a list of globs or the full watch config
+func (w *WatchConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&w.Include); err == nil {
+		return nil
+	}
+
+	type plain WatchConfig
+	return unmarshal((*plain)(w))
+}
+
+// idleTimeout returns how long the app can go without a request before it is
+// stopped, falling back to the global setting
+func (c *AppConfig) idleTimeout() time.Duration {
+	if c.IdleTimeout != 0 {
+		return c.IdleTimeout
+	}
+	return globalConfig.IdleTimeout
+}
+
+// stopTimeout returns how long the app has to shut down before it is killed,
+// falling back to the global setting
+func (c *AppConfig) stopTimeout() time.Duration {
+	if c.StopTimeout != 0 {
+		return c.StopTimeout
+	}
+	return globalConfig.StopTimeout
+}
+
+// logFile returns the path the app output is written to, if any
+func (c *AppConfig) logFile() string {
+	if globalConfig.LogDir == "" {
+		return ""
+	}
+	return filepath.Join(homedir.MustExpand(globalConfig.LogDir), c.Host+".log")
+}
+
+// bootTimeout returns how long the app has to become available, falling back
+// to the global setting
+func (c *AppConfig) bootTimeout() time.Duration {
+	if c.BootTimeout != 0 {
+		return c.BootTimeout
+	}
+	return globalConfig.BootTimeout
 }
 
 func getAppConfig(host string) (*AppConfig, error) {
@@ -50,6 +167,7 @@ func readConfigFromFile(path, host string) (*AppConfig, error) {
 	}
 
 	config.Host = host
+	config.Path = path
 	config.Key = config.Dir
 	if config.Key == "" {
 		// FIXME: host is coded in the proxy so we need one per host source
@@ -75,3 +193,38 @@ func getClosestMatchingPath(host string) (string, error) {
 	}
 	return path, nil
 }
+
+// diffAppConfig returns a human readable list of the settings that differ
+// between two configs, eg. `command: "mix phx.server" -> "iex -S mix phx.server"`
+func diffAppConfig(old, new *AppConfig) []string {
+	changes := []string{}
+
+	ov := reflect.ValueOf(*old)
+	nv := reflect.ValueOf(*new)
+	t := ov.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Name == "Host" {
+			continue
+		}
+
+		o := ov.Field(i).Interface()
+		n := nv.Field(i).Interface()
+		if reflect.DeepEqual(o, n) {
+			continue
+		}
+
+		changes = append(changes, fmt.Sprintf("%s: %#v -> %#v", configKey(field), o, n))
+	}
+
+	return changes
+}
+
+// configKey returns the YAML key used for the given AppConfig field
+func configKey(field reflect.StructField) string {
+	if tag := strings.Split(field.Tag.Get("yaml"), ",")[0]; tag != "" {
+		return tag
+	}
+	return strings.ToLower(field.Name)
+}
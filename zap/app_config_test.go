@@ -0,0 +1,84 @@
+package zap
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestDiffAppConfig(t *testing.T) {
+	old := &AppConfig{Host: "moo.test", Dir: "/code/moo", Command: "mix phx.server"}
+	new := &AppConfig{Host: "www.moo.test", Dir: "/code/moo", Command: "iex -S mix phx.server"}
+
+	changes := diffAppConfig(old, new)
+	if len(changes) != 1 {
+		t.Fatal("expected one change, got", changes)
+	}
+
+	if changes[0] != `command: "mix phx.server" -> "iex -S mix phx.server"` {
+		t.Error("unexpected change", changes[0])
+	}
+
+	if len(diffAppConfig(old, old)) != 0 {
+		t.Error("expected no changes for identical configs")
+	}
+}
+
+func TestRestartOnConfig(t *testing.T) {
+	config := &AppConfig{}
+	err := yaml.Unmarshal([]byte(`
+restart_on:
+  - "Compilation error"
+  - pattern: "panic:"
+    stream: stderr
+`), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []RestartPattern{
+		{Pattern: "Compilation error"},
+		{Pattern: "panic:", Stream: "stderr"},
+	}
+	if !reflect.DeepEqual(config.RestartOn, expected) {
+		t.Error("unexpected restart_on", config.RestartOn)
+	}
+}
+
+func TestReadyPathConfig(t *testing.T) {
+	config := &AppConfig{Host: "moo.test", Command: "bin/server", ReadyPath: "health"}
+	if _, err := getServerConfig(config, nil); err == nil {
+		t.Error("expected an error for a ready_path without a leading /")
+	}
+
+	config.ReadyPath = "/health"
+	if _, err := getServerConfig(config, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLifecycleConfig(t *testing.T) {
+	config := &AppConfig{}
+	if err := yaml.Unmarshal([]byte("idle_timeout: 2h\nboot_timeout: 90s\n"), config); err != nil {
+		t.Fatal(err)
+	}
+	if config.idleTimeout() != 2*time.Hour || config.bootTimeout() != 90*time.Second {
+		t.Error("expected the app settings, got", config.idleTimeout(), config.bootTimeout())
+	}
+
+	defaults := &AppConfig{}
+	if defaults.idleTimeout() != globalConfig.IdleTimeout || defaults.bootTimeout() != globalConfig.BootTimeout {
+		t.Error("expected the global settings, got", defaults.idleTimeout(), defaults.bootTimeout())
+	}
+
+	a := &app{Config: config, LastUsed: time.Now().Add(-3 * time.Hour)}
+	if !a.idle() {
+		t.Error("expected the app to be idle after its idle_timeout")
+	}
+	config.AlwaysOn = true
+	if a.idle() {
+		t.Error("expected an always_on app never to be idle")
+	}
+}
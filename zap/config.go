@@ -0,0 +1,162 @@
+package zap
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/puma/puma-dev/homedir"
+	"github.com/vektra/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigPath is the default location of the global zapd config file
+const ConfigPath = appsPath + "/" + configFile
+
+const configFile = "zapd.yaml"
+
+// Config holds the global zapd configuration
+type Config struct {
+	HTTP            string        `yaml:"http"`
+	HTTPS           string        `yaml:"https"`
+	DNS             string        `yaml:"dns"`
+	Domains         []string      `yaml:"domains"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	BootTimeout     time.Duration `yaml:"boot_timeout"`
+	StopTimeout     time.Duration `yaml:"stop_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	NgrokRegion     string        `yaml:"ngrok_region"`
+	CertCacheSize   int           `yaml:"cert_cache_size"`
+	LogDir          string        `yaml:"log_dir"`
+	LogMaxSize      int64         `yaml:"log_max_size"`
+	LogMaxFiles     int           `yaml:"log_max_files"`
+
+	CrashLoopRestarts int           `yaml:"crash_loop_restarts"`
+	CrashLoopWindow   time.Duration `yaml:"crash_loop_window"`
+	CrashLoopBackoff  time.Duration `yaml:"crash_loop_backoff"`
+
+	Orphans string `yaml:"orphans"`
+
+	PortRange string `yaml:"port_range"`
+}
+
+// globalConfig is the configuration used by the running server
+var globalConfig = DefaultConfig()
+
+// DefaultConfig returns the configuration used when no config file exists
+func DefaultConfig() *Config {
+	return &Config{
+		HTTP:            "127.0.0.1:80",
+		HTTPS:           "127.0.0.1:443",
+		DNS:             "127.0.0.1:9253",
+		Domains:         []string{"dev", "test"},
+		IdleTimeout:     60 * time.Minute,
+		BootTimeout:     60 * time.Second,
+		StopTimeout:     5 * time.Second,
+		ShutdownTimeout: 30 * time.Second,
+		NgrokRegion:     "eu",
+		CertCacheSize:   1024,
+		LogDir:          appsPath + "/logs",
+		LogMaxSize:      10 * 1024 * 1024,
+		LogMaxFiles:     5,
+
+		CrashLoopRestarts: 5,
+		CrashLoopWindow:   time.Minute,
+		CrashLoopBackoff:  10 * time.Second,
+
+		Orphans: orphansReap,
+	}
+}
+
+// LoadConfig reads the config file at the given path on top of the defaults,
+// a missing file is not an error
+func LoadConfig(path string) (*Config, error) {
+	config := DefaultConfig()
+
+	data, err := ioutil.ReadFile(homedir.MustExpand(path))
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, errors.Context(err, "reading config")
+	}
+
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, errors.Context(err, "parsing config")
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// validate checks the settings that would otherwise fail deep inside the
+// server or silently behave differently
+func (c *Config) validate() error {
+	durations := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"idle_timeout", c.IdleTimeout},
+		{"boot_timeout", c.BootTimeout},
+		{"stop_timeout", c.StopTimeout},
+		{"shutdown_timeout", c.ShutdownTimeout},
+		{"crash_loop_window", c.CrashLoopWindow},
+		{"crash_loop_backoff", c.CrashLoopBackoff},
+	}
+	for _, d := range durations {
+		if d.value <= 0 {
+			return errors.Format("%s must be greater than 0, got %s", d.key, d.value)
+		}
+	}
+
+	if c.CertCacheSize < 1 {
+		return errors.Format("cert_cache_size must be at least 1, got %d", c.CertCacheSize)
+	}
+	if c.CrashLoopRestarts < 1 {
+		return errors.Format("crash_loop_restarts must be at least 1, got %d", c.CrashLoopRestarts)
+	}
+	if c.LogMaxSize < 0 || c.LogMaxFiles < 0 {
+		return errors.New("log_max_size and log_max_files can't be negative")
+	}
+
+	switch c.Orphans {
+	case orphansReap, orphansAdopt:
+	default:
+		return errors.Format("invalid orphans %q, expected %s or %s", c.Orphans, orphansReap, orphansAdopt)
+	}
+
+	_, _, err := c.portRange()
+	return err
+}
+
+// portRange returns the first and last ports apps are given, both are 0 if
+// no range is set
+func (c *Config) portRange() (int, int, error) {
+	if c.PortRange == "" {
+		return 0, 0, nil
+	}
+
+	parts := strings.SplitN(c.PortRange, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, errors.Format("invalid port_range %q, expected eg. 4000-4999", c.PortRange)
+	}
+
+	from, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, errors.Format("invalid port_range %q, expected eg. 4000-4999", c.PortRange)
+	}
+	to, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0, 0, errors.Format("invalid port_range %q, expected eg. 4000-4999", c.PortRange)
+	}
+
+	if from < 1 || to > 65535 || from > to {
+		return 0, 0, errors.Format("invalid port_range %q", c.PortRange)
+	}
+	return from, to, nil
+}
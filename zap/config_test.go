@@ -0,0 +1,61 @@
+package zap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zap-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, configFile)
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(config, DefaultConfig()) {
+		t.Error("expected the defaults without a config file, got", config)
+	}
+
+	write := func(data string) {
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("http: 127.0.0.1:8080\nidle_timeout: 5m\norphans: adopt\nport_range: 4000-4999\n")
+	config, err = LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.HTTP != "127.0.0.1:8080" || config.IdleTimeout != 5*time.Minute || config.Orphans != orphansAdopt {
+		t.Error("expected the file to override the defaults, got", config)
+	}
+	if config.HTTPS != DefaultConfig().HTTPS || config.CertCacheSize != DefaultConfig().CertCacheSize {
+		t.Error("expected the defaults for settings not in the file, got", config)
+	}
+
+	for _, data := range []string{
+		"cert_cache_size: 0\n",
+		"crash_loop_restarts: 0\n",
+		"orphans: adpot\n",
+		"stop_timeout: 0s\n",
+		"shutdown_timeout: -5s\n",
+		"log_max_files: -1\n",
+		"port_range: 5000-4000\n",
+		"idle_timeout: [\n",
+	} {
+		write(data)
+		if _, err := LoadConfig(path); err == nil {
+			t.Errorf("expected an error loading %q", data)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package zap
+
+import (
+	"log"
+	"time"
+
+	"github.com/moomerman/zap/adapter"
+)
+
+const statusCrashLoop = "crashloop"
+
+// maxCrashLoopBackoff caps how long a crashing app waits between restarts
+const maxCrashLoopBackoff = 5 * time.Minute
+
+// crashed records that the adapter exited on its own and reports whether the
+// app is in a crash loop and should not be restarted yet
+func (a *app) crashed() bool {
+	a.crashMu.Lock()
+	defer a.crashMu.Unlock()
+
+	now := time.Now()
+
+	if a.exitedAdapter != a.Adapter {
+		a.exitedAdapter = a.Adapter
+		a.recordExit(now)
+	}
+
+	return now.Before(a.CrashLoopUntil)
+}
+
+// recordExit adds an exit to the history and enters the crash loop backoff
+// once too many exits have happened within the window, an app that crashes
+// again within the window after a backoff doubles it
+func (a *app) recordExit(now time.Time) {
+	window := globalConfig.CrashLoopWindow
+
+	recent := []time.Time{}
+	for _, exit := range a.Exits {
+		if now.Sub(exit) < window {
+			recent = append(recent, exit)
+		}
+	}
+	a.Exits = append(recent, now)
+
+	switch {
+	case a.backoff > 0 && now.Sub(a.CrashLoopUntil) < window:
+		a.backoff *= 2
+		if a.backoff > maxCrashLoopBackoff {
+			a.backoff = maxCrashLoopBackoff
+		}
+	case len(a.Exits) >= globalConfig.CrashLoopRestarts:
+		a.backoff = globalConfig.CrashLoopBackoff
+	default:
+		a.backoff = 0
+		return
+	}
+
+	a.CrashLoopUntil = now.Add(a.backoff)
+	log.Println("[app]", a.config().Host, "exited", len(a.Exits), "times in", window, "backing off for", a.backoff)
+}
+
+// inCrashLoop reports whether the app is waiting to be restarted
+func (a *app) inCrashLoop() bool {
+	a.crashMu.Lock()
+	defer a.crashMu.Unlock()
+	return time.Now().Before(a.CrashLoopUntil) && a.Adapter.Status() == adapter.StatusStopped
+}
+
+// clearCrashLoop forgets the exit history so the app can restart immediately
+func (a *app) clearCrashLoop() {
+	a.crashMu.Lock()
+	defer a.crashMu.Unlock()
+	a.Exits = nil
+	a.CrashLoopUntil = time.Time{}
+	a.backoff = 0
+}
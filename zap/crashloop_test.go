@@ -0,0 +1,69 @@
+package zap
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRecordExit(t *testing.T) {
+	a := &app{Config: &AppConfig{Host: "moo.test"}}
+	now := time.Now()
+
+	for i := 0; i < globalConfig.CrashLoopRestarts-1; i++ {
+		a.recordExit(now)
+	}
+	if !a.CrashLoopUntil.IsZero() {
+		t.Fatal("expected no backoff before the restart limit")
+	}
+
+	a.recordExit(now)
+	if a.backoff != globalConfig.CrashLoopBackoff {
+		t.Fatal("expected initial backoff, got", a.backoff)
+	}
+
+	now = a.CrashLoopUntil.Add(time.Second)
+	a.recordExit(now)
+	if a.backoff != 2*globalConfig.CrashLoopBackoff {
+		t.Fatal("expected backoff to double, got", a.backoff)
+	}
+
+	now = a.CrashLoopUntil.Add(globalConfig.CrashLoopWindow)
+	a.recordExit(now)
+	if a.backoff != 0 {
+		t.Fatal("expected backoff to reset after a stable run, got", a.backoff)
+	}
+}
+
+func TestMarshalAppWhileCrashing(t *testing.T) {
+	a := &app{Config: &AppConfig{Host: "moo.test"}}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			a.crashMu.Lock()
+			a.recordExit(time.Now())
+			a.crashMu.Unlock()
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		if _, err := json.Marshal(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+
+	var state struct{ Exits []time.Time }
+	content, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(content, &state); err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Exits) == 0 {
+		t.Fatal("expected the exits to be marshalled")
+	}
+}
@@ -0,0 +1,132 @@
+package zap
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/moomerman/zap/adapter"
+	"github.com/vektra/errors"
+)
+
+// statusWaiting is the status of an app that is waiting for the apps it
+// depends on to start
+const statusWaiting = "waiting"
+
+// checkDependencies returns an error if any of the apps the config depends
+// on (directly or indirectly) can't be found or depend on each other, path
+// is the chain of hosts that led to this config
+func checkDependencies(config *AppConfig, path []string) error {
+	for _, host := range config.DependsOn {
+		for _, seen := range path {
+			if seen == host {
+				return errors.Format("dependency cycle %s", strings.Join(append(path, host), " -> "))
+			}
+		}
+
+		dep, err := getAppConfig(host)
+		if err != nil {
+			return errors.Context(err, "dependency "+host+" not found")
+		}
+
+		if err := checkDependencies(dep, append(path, host)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startAfterDependencies starts the apps this app depends on and boots the
+// app in the background once they're all running
+func (a *app) startAfterDependencies() {
+	a.dependsMu.Lock()
+	a.Waiting = true
+	a.DependencyError = ""
+	a.dependsMu.Unlock()
+
+	go a.waitForDependencies()
+}
+
+func (a *app) waitForDependencies() {
+	log.Println("[app]", a.config().Host, "waiting for", a.config().DependsOn)
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(a.config().bootTimeout())
+
+	for {
+		host, status := a.blockingDependency()
+		if host == "" {
+			break
+		}
+		a.blockedOn(host, status)
+
+		select {
+		case <-ticker.C:
+		case <-timeout:
+			log.Println("[app]", a.config().Host, "timed out waiting for", host, status)
+			a.dependsMu.Lock()
+			a.Waiting = false
+			a.DependencyError = fmt.Sprintf("timed out waiting for %s (%s)", host, status)
+			a.dependsMu.Unlock()
+			return
+		}
+	}
+
+	appsMu.Lock()
+	current := apps[a.config().Key] == a
+	appsMu.Unlock()
+	if !current {
+		log.Println("[app]", a.config().Host, "removed while waiting for dependencies")
+		return
+	}
+
+	log.Println("[app]", a.config().Host, "dependencies running, starting")
+	if err := a.boot(); err != nil {
+		log.Println("[app]", a.config().Host, "error starting app", err)
+	}
+
+	a.dependsMu.Lock()
+	a.Waiting = false
+	a.WaitingFor = ""
+	a.WaitingStatus = ""
+	a.dependsMu.Unlock()
+}
+
+// blockingDependency returns the first dependency that isn't running yet and
+// its status, dependencies that have stopped are started again
+func (a *app) blockingDependency() (string, string) {
+	for _, host := range a.config().DependsOn {
+		dep, err := findAppForHost(host)
+		if err != nil {
+			return host, err.Error()
+		}
+		if status := dep.Status(); status != string(adapter.StatusRunning) {
+			return host, status
+		}
+	}
+	return "", ""
+}
+
+func (a *app) blockedOn(host, status string) {
+	a.dependsMu.Lock()
+	defer a.dependsMu.Unlock()
+	a.WaitingFor = host
+	a.WaitingStatus = status
+}
+
+// waiting reports whether the app is waiting for its dependencies or gave up
+// waiting for them
+func (a *app) waiting() (bool, bool) {
+	a.dependsMu.Lock()
+	defer a.dependsMu.Unlock()
+	return a.Waiting, a.DependencyError != ""
+}
+
+// boot starts the adapter, replacing it if it has already been stopped
+func (a *app) boot() error {
+	if a.Adapter.Status() == adapter.StatusStopped {
+		return a.RestartAdapter()
+	}
+	return a.Start()
+}
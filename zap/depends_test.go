@@ -0,0 +1,51 @@
+package zap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/puma/puma-dev/homedir"
+)
+
+func TestCheckDependencies(t *testing.T) {
+	home, err := ioutil.TempDir("", "zap-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	homedir.DisableCache = true
+	defer func() { homedir.DisableCache = false }()
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", home)
+
+	dir := filepath.Join(home, ".zap")
+	os.Mkdir(dir, 0755)
+
+	write := func(host, config string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, host), []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("web.test", "proxy: http://127.0.0.1:3000\ndepends_on: [api.test, auth.test]\n")
+	write("api.test", "proxy: http://127.0.0.1:4000\ndepends_on: [auth.test]\n")
+	write("auth.test", "proxy: http://127.0.0.1:5000\n")
+
+	config, err := getAppConfig("web.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkDependencies(config, []string{"web.test"}); err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	write("auth.test", "proxy: http://127.0.0.1:5000\ndepends_on: [web.test]\n")
+	err = checkDependencies(config, []string{"web.test"})
+	if err == nil || !strings.Contains(err.Error(), "web.test -> api.test -> auth.test -> web.test") {
+		t.Fatal("expected a dependency cycle, got", err)
+	}
+}
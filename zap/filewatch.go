@@ -0,0 +1,295 @@
+package zap
+
+import (
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vektra/errors"
+)
+
+// watch defaults, how often the app dir is polled and how long the files
+// have to stay unchanged before the app is restarted
+const (
+	defaultWatchInterval = time.Second
+	defaultWatchDebounce = 500 * time.Millisecond
+)
+
+// watchAlwaysExcluded is never searched for watched files
+var watchAlwaysExcluded = []string{".git"}
+
+// watchDefaultExcluded are dependency and build output dirs that are large
+// and not edited by hand, they aren't searched unless an include glob starts
+// with one
+var watchDefaultExcluded = []string{"node_modules", "deps", "_build"}
+
+// fileWatcher polls the app dir for changes to the watched files and
+// rebuilds and restarts the app once they settle. The dir and config are
+// fixed when it's created, a reload that changes them starts a new watcher
+type fileWatcher struct {
+	app    *app
+	dir    string
+	config WatchConfig
+	done   chan struct{}
+}
+
+func newFileWatcher(a *app) *fileWatcher {
+	return &fileWatcher{
+		app:    a,
+		dir:    a.config().Dir,
+		config: a.config().Watch,
+		done:   make(chan struct{}),
+	}
+}
+
+// Watch polls the app dir until Stop is called
+func (w *fileWatcher) Watch() {
+	config := w.config
+	log.Println("[app]", w.app.config().Host, "watching", config.Include, "for changes")
+
+	files := scanWatched(w.dir, config)
+	changed := map[string]bool{}
+
+	var settled <-chan time.Time
+	ticker := time.NewTicker(config.interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			next := scanWatched(w.dir, config)
+			names := changedFiles(files, next)
+			if len(names) == 0 {
+				continue
+			}
+			for _, name := range names {
+				changed[name] = true
+			}
+			files = next
+			settled = time.After(config.debounce())
+		case <-settled:
+			settled = nil
+			w.rebuild(sortedKeys(changed))
+			changed = map[string]bool{}
+		}
+	}
+}
+
+// Stop stops watching the app dir
+func (w *fileWatcher) Stop() {
+	close(w.done)
+}
+
+// rebuild runs the watch build command while the app keeps serving and then
+// replaces the app process, or restarts it if it can't be replaced. A failed
+// build leaves the app running as it was
+func (w *fileWatcher) rebuild(changed []string) {
+	a := w.app
+
+	a.adapterMu.Lock()
+	adpt := a.Adapter
+	current := a.watcher == w
+	a.adapterMu.Unlock()
+
+	// the app may have been stopped or reloaded since the files changed
+	if !current {
+		return
+	}
+	log.Println("[app]", a.config().Host, "files changed", changed)
+
+	if build := w.config.Build; build != "" {
+		if b, ok := adpt.(interface {
+			Build(string) error
+		}); ok {
+			err := b.Build(build)
+			a.setBuildError(err)
+			if err != nil {
+				log.Println("[app]", a.config().Host, "build failed, not restarting", err)
+				return
+			}
+		}
+	}
+
+	a.clearCrashLoop()
+
+	// replaced whether or not zero_downtime is set so the app keeps serving
+	// until the new build is ready
+	if replaced, err := a.replace(); replaced {
+		if err != nil {
+			log.Println("[app]", a.config().Host, "error replacing after files changed", err)
+		}
+		return
+	}
+
+	a.adapterMu.Lock()
+	defer a.adapterMu.Unlock()
+
+	// the app may have been stopped while building
+	if a.watcher != w {
+		return
+	}
+	if err := a.restartAdapter(); err != nil {
+		log.Println("[app]", a.config().Host, "error restarting after files changed", err)
+	}
+}
+
+func (a *app) setBuildError(err error) {
+	a.buildMu.Lock()
+	defer a.buildMu.Unlock()
+	a.BuildError = ""
+	if err != nil {
+		a.BuildError = err.Error()
+	}
+}
+
+func (c WatchConfig) interval() time.Duration {
+	if c.Interval > 0 {
+		return c.Interval
+	}
+	return defaultWatchInterval
+}
+
+func (c WatchConfig) debounce() time.Duration {
+	if c.Debounce > 0 {
+		return c.Debounce
+	}
+	return defaultWatchDebounce
+}
+
+// scanWatched returns the modification times of the files under dir that
+// match an include glob and no exclude glob, keyed by their slash separated
+// path relative to dir. Excluded directories aren't searched
+func scanWatched(dir string, config WatchConfig) map[string]time.Time {
+	files := map[string]time.Time{}
+	if len(config.Include) == 0 {
+		return files
+	}
+
+	defaults := []string{}
+	for _, name := range watchDefaultExcluded {
+		included := false
+		for _, pattern := range config.Include {
+			if strings.HasPrefix(pattern, name+"/") {
+				included = true
+			}
+		}
+		if !included {
+			defaults = append(defaults, name)
+		}
+	}
+
+	excluded := func(name string) bool {
+		return matchAny(watchAlwaysExcluded, name) || matchAny(defaults, name) || matchAny(config.Exclude, name)
+	}
+
+	filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return nil
+		}
+		name := filepath.ToSlash(rel)
+
+		if info.IsDir() {
+			if excluded(name) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if matchAny(config.Include, name) && !excluded(name) {
+			files[name] = info.ModTime()
+		}
+		return nil
+	})
+
+	return files
+}
+
+// changedFiles returns the names of the files added, changed or removed
+func changedFiles(old, new map[string]time.Time) []string {
+	names := []string{}
+	for name, modTime := range new {
+		if previous, ok := old[name]; !ok || !previous.Equal(modTime) {
+			names = append(names, name)
+		}
+	}
+	for name := range old {
+		if _, ok := new[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches a slash separated path against a glob where ** matches
+// any number of directories. A glob without a slash matches the base name of
+// a path at any depth
+func matchGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// validateWatch checks the watch globs are well formed
+func validateWatch(config WatchConfig) error {
+	for _, pattern := range append(append([]string{}, config.Include...), config.Exclude...) {
+		for _, segment := range strings.Split(pattern, "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return errors.Format("watch: invalid glob %q", pattern)
+			}
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
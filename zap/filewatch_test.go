@@ -0,0 +1,151 @@
+package zap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/server/main.go", true},
+		{"*.go", "main.go.orig", false},
+		{"go.mod", "go.mod", true},
+		{"templates/*.html", "templates/index.html", true},
+		{"templates/*.html", "templates/admin/index.html", false},
+		{"templates/**/*.html", "templates/index.html", true},
+		{"templates/**/*.html", "templates/admin/index.html", true},
+		{"vendor/**", "vendor", true},
+		{"vendor/**", "vendor/github.com/pkg/errors/errors.go", true},
+		{"vendor/**", "internal/vendor/x.go", false},
+		{"**/testdata/**", "pkg/testdata/fixture.json", true},
+	}
+
+	for _, test := range tests {
+		if match := matchGlob(test.pattern, test.name); match != test.match {
+			t.Error(test.pattern, test.name, "expected", test.match, "got", match)
+		}
+	}
+}
+
+func TestScanWatched(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zap-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"main.go", "go.mod", "README.md", "cmd/app/main.go", "vendor/pkg/pkg.go", ".git/HEAD", "node_modules/dep/index.go", "deps/lib/lib.go"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := WatchConfig{Include: []string{"*.go", "go.mod", "HEAD"}, Exclude: []string{"vendor/**"}}
+	files := scanWatched(dir, config)
+
+	expected := []string{"cmd/app/main.go", "go.mod", "main.go"}
+	if len(files) != len(expected) {
+		t.Fatal("expected", expected, "got", files)
+	}
+
+	// a default excluded dir is searched when an include glob names it
+	deps := scanWatched(dir, WatchConfig{Include: []string{"deps/**/*.go"}})
+	if _, ok := deps["deps/lib/lib.go"]; !ok || len(deps) != 1 {
+		t.Error("expected only deps/lib/lib.go to be watched, got", deps)
+	}
+	for _, name := range expected {
+		if _, ok := files[name]; !ok {
+			t.Error("expected", name, "to be watched")
+		}
+	}
+
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(dir, "main.go"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "go.mod")); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := changedFiles(files, scanWatched(dir, config))
+	if len(changed) != 2 {
+		t.Fatal("expected main.go and go.mod to have changed, got", changed)
+	}
+}
+
+func TestWatchConfigYAML(t *testing.T) {
+	config := &AppConfig{}
+	if err := yaml.Unmarshal([]byte("watch: ['*.go', go.mod]\n"), config); err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Watch.Include) != 2 || config.Watch.Build != "" {
+		t.Error("expected a list to set the include globs, got", config.Watch)
+	}
+
+	config = &AppConfig{}
+	data := "watch:\n  include: ['*.go']\n  exclude: [tmp]\n  build: go build -o tmp/app .\n  debounce: 1s\n"
+	if err := yaml.Unmarshal([]byte(data), config); err != nil {
+		t.Fatal(err)
+	}
+	if config.Watch.Build != "go build -o tmp/app ." || config.Watch.Debounce != time.Second || len(config.Watch.Exclude) != 1 {
+		t.Error("unexpected watch config", config.Watch)
+	}
+
+	if err := validateWatch(WatchConfig{Include: []string{"src/[a-"}}); err == nil {
+		t.Error("expected an invalid glob to be an error")
+	}
+}
+
+func TestReloadRestartsWatcher(t *testing.T) {
+	config := &AppConfig{Host: "moo.test", Key: "moo.test", Proxy: "http://127.0.0.1:3000", Watch: WatchConfig{Include: []string{"*.go"}}}
+	a, err := newApp(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer a.Stop("test finished", nil)
+
+	watcher := a.watcher
+	if watcher == nil {
+		t.Fatal("expected the app to be watched")
+	}
+
+	changed := *config
+	changed.Watch = WatchConfig{Include: []string{"*.html"}}
+	if err := a.Reload(&changed); err != nil {
+		t.Fatal(err)
+	}
+	if a.watcher == nil || a.watcher == watcher || a.watcher.config.Include[0] != "*.html" {
+		t.Fatal("expected a new watcher for the new globs")
+	}
+	select {
+	case <-watcher.done:
+	default:
+		t.Error("expected the old watcher to be stopped")
+	}
+
+	removed := changed
+	removed.Watch = WatchConfig{}
+	if err := a.Reload(&removed); err != nil {
+		t.Fatal(err)
+	}
+	if a.watcher != nil {
+		t.Error("expected no watcher once watch is removed")
+	}
+}
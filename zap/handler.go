@@ -3,11 +3,21 @@ package zap
 import (
 	"context"
 	"encoding/json"
+	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/moomerman/zap/adapter"
+	"github.com/moomerman/zap/logfile"
 	"github.com/unrolled/render"
+	"github.com/vektra/errors"
+	"golang.org/x/net/websocket"
 )
 
 type contextKey string
@@ -40,12 +50,30 @@ func findAppHandler(next http.HandlerFunc) http.HandlerFunc {
 func appHandler(w http.ResponseWriter, r *http.Request) {
 	app := r.Context().Value(appKey).(*app)
 
+	if route := app.findRoute(r.URL.Path); route != nil {
+		app.touch()
+		route.ServeHTTP(w, r)
+		return
+	}
+
 	switch app.Status() {
-	case "running":
+	case "running", "unhealthy":
 		app.ServeHTTP(w, r)
-	default:
+		return
+	case "starting", statusWaiting:
+		if app.config().HoldRequests && app.hold(r.Context()) {
+			app.ServeHTTP(w, r)
+			return
+		}
+	}
+
+	if acceptsHTML(r) {
 		renderer.HTML(w, http.StatusAccepted, "app", app)
+		return
 	}
+
+	w.Header().Set("Retry-After", retryAfter)
+	http.Error(w, "503 Service Unavailable: app is "+app.Status(), http.StatusServiceUnavailable)
 }
 
 func statusHandler(w http.ResponseWriter, r *http.Request) {
@@ -55,14 +83,25 @@ func statusHandler(w http.ResponseWriter, r *http.Request) {
 
 func logHandler(w http.ResponseWriter, r *http.Request) {
 	app := r.Context().Value(appKey).(*app)
-	renderer.HTML(w, http.StatusOK, "log", app)
+
+	filter, err := newLogFilter(r)
+	if err != nil {
+		http.Error(w, "400 Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	renderer.HTML(w, http.StatusOK, "log", map[string]interface{}{
+		"App":   app,
+		"Lines": filter.Lines(app.LogLines()),
+	})
 }
 
 func restartHandler(w http.ResponseWriter, r *http.Request) {
 	app := r.Context().Value(appKey).(*app)
 
+	app.clearCrashLoop()
 	if err := app.RestartAdapter(); err != nil {
-		log.Println("[app]", app.Config.Host, "internal server error", err)
+		log.Println("[app]", app.config().Host, "internal server error", err)
 		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
 	}
 
@@ -72,7 +111,223 @@ func restartHandler(w http.ResponseWriter, r *http.Request) {
 func logAPIHandler(w http.ResponseWriter, r *http.Request) {
 	app := r.Context().Value(appKey).(*app)
 
-	app.WriteLog(w)
+	query := r.URL.Query()
+	if query.Get("generation") == "" && query.Get("since") == "" {
+		filter, err := newLogFilter(r)
+		if err != nil {
+			http.Error(w, "400 Bad Request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if filter.Empty() {
+			app.WriteLog(w)
+			return
+		}
+		for _, line := range filter.Lines(app.LogLines()) {
+			io.WriteString(w, line.Text)
+		}
+		return
+	}
+
+	path := app.config().logFile()
+	if path == "" {
+		http.Error(w, "404 Log Files Disabled", http.StatusNotFound)
+		return
+	}
+
+	if since := query.Get("since"); since != "" {
+		t, err := parseSince(since)
+		if err != nil {
+			http.Error(w, "400 Bad Request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := logfile.WriteSince(w, path, globalConfig.LogMaxFiles, t); err != nil {
+			log.Println("[app]", app.config().Host, "error reading log files", err)
+		}
+		return
+	}
+
+	generation, err := strconv.Atoi(query.Get("generation"))
+	if err != nil || generation < 0 || generation > globalConfig.LogMaxFiles {
+		http.Error(w, "400 Bad Request: invalid generation", http.StatusBadRequest)
+		return
+	}
+	if err := logfile.WriteGeneration(w, path, generation); err != nil {
+		http.Error(w, "404 Not Found", http.StatusNotFound)
+	}
+}
+
+// logStreamAPIHandler follows the app log, sending new lines as server-sent
+// events (with the stream as the event type) or as JSON websocket messages if
+// the client asks to upgrade, lines can be filtered with ?filter=, ?stream=
+// and ?process=
+func logStreamAPIHandler(w http.ResponseWriter, r *http.Request) {
+	app := r.Context().Value(appKey).(*app)
+
+	filter, err := newLogFilter(r)
+	if err != nil {
+		http.Error(w, "400 Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lines := app.logs.Subscribe()
+	defer app.logs.Unsubscribe(lines)
+
+	follow := func(done <-chan struct{}, send func(line *adapter.LogLine) error) {
+		keepAlive := time.NewTicker(15 * time.Second)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-keepAlive.C:
+				if err := send(nil); err != nil {
+					return
+				}
+			case line := <-lines:
+				if !filter.Match(line) {
+					continue
+				}
+				if err := send(&line); err != nil {
+					return
+				}
+			}
+		}
+	}
+
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		// clients that don't send an Origin header, eg. command line tools,
+		// are accepted but web pages can only follow logs on their own host
+		websocket.Server{Handshake: func(config *websocket.Config, req *http.Request) error {
+			return checkOrigin(req)
+		}, Handler: func(ws *websocket.Conn) {
+			done := make(chan struct{})
+			go func() {
+				// the connection is closed when the client goes away
+				io.Copy(ioutil.Discard, ws)
+				close(done)
+			}()
+
+			follow(done, func(line *adapter.LogLine) error {
+				if line == nil {
+					return nil
+				}
+				return websocket.JSON.Send(ws, line)
+			})
+		}}.ServeHTTP(w, r)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "500 Streaming Unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	follow(r.Context().Done(), func(line *adapter.LogLine) error {
+		var err error
+		if line == nil {
+			_, err = io.WriteString(w, ": keep-alive\n\n")
+		} else {
+			_, err = io.WriteString(w, "event: "+line.Stream+"\ndata: "+processPrefix(line)+strings.TrimRight(line.Text, "\r\n")+"\n\n")
+		}
+		flusher.Flush()
+		return err
+	})
+}
+
+// logFilter selects log lines by ?process=, ?stream= and ?filter= (a regular
+// expression)
+type logFilter struct {
+	Process string
+	Stream  string
+	Pattern *regexp.Regexp
+}
+
+func newLogFilter(r *http.Request) (*logFilter, error) {
+	query := r.URL.Query()
+	filter := &logFilter{Process: query.Get("process"), Stream: query.Get("stream")}
+
+	if filter.Stream != "" && filter.Stream != adapter.StreamStdout && filter.Stream != adapter.StreamStderr {
+		return nil, errors.Format("unknown stream %q", filter.Stream)
+	}
+
+	if expr := query.Get("filter"); expr != "" {
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		filter.Pattern = pattern
+	}
+
+	return filter, nil
+}
+
+// Empty reports whether the filter matches every line
+func (f *logFilter) Empty() bool {
+	return f.Process == "" && f.Stream == "" && f.Pattern == nil
+}
+
+// Match reports whether the line passes the filter
+func (f *logFilter) Match(line adapter.LogLine) bool {
+	if f.Process != "" && line.Process != f.Process {
+		return false
+	}
+	if f.Stream != "" && line.Stream != f.Stream {
+		return false
+	}
+	return f.Pattern == nil || f.Pattern.MatchString(line.Text)
+}
+
+// Lines returns the lines that pass the filter
+func (f *logFilter) Lines(lines []adapter.LogLine) []adapter.LogLine {
+	matched := []adapter.LogLine{}
+	for _, line := range lines {
+		if f.Match(line) {
+			matched = append(matched, line)
+		}
+	}
+	return matched
+}
+
+// processPrefix labels lines from Procfile processes other than web
+func processPrefix(line *adapter.LogLine) string {
+	if line.Process == "" || line.Process == "web" {
+		return ""
+	}
+	return "[" + line.Process + "] "
+}
+
+// parseSince accepts either a time (RFC3339) or a duration ago (eg. 10m)
+func parseSince(since string) (time.Time, error) {
+	if d, err := time.ParseDuration(since); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, since)
+}
+
+// checkOrigin returns an error if the request has an Origin header for a
+// host other than the one requested, so a page on another site can't read
+// the app logs
+func checkOrigin(r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return errors.Context(err, "invalid origin")
+	}
+	if !strings.EqualFold(u.Host, r.Host) {
+		return errors.Format("origin %s not allowed", origin)
+	}
+	return nil
 }
 
 // NGROK HANDLERS
@@ -87,7 +342,7 @@ func startNgrokHandler(w http.ResponseWriter, r *http.Request) {
 	app := r.Context().Value(appKey).(*app)
 
 	if err := app.StartNgrok(r.Host, 80); err != nil {
-		log.Println("[app]", app.Config.Host, "internal server error", err)
+		log.Println("[app]", app.config().Host, "internal server error", err)
 		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
 	}
 
@@ -103,9 +358,10 @@ func stateAPIHandler(w http.ResponseWriter, r *http.Request) {
 		"app":    app,
 		"uptime": time.Since(app.Started).String(),
 		"status": app.Status(),
+		"routes": app.RouteStatus(),
 	}, "", "  ")
 	if err != nil {
-		log.Println("[app]", app.Config.Host, "internal server error", err)
+		log.Println("[app]", app.config().Host, "internal server error", err)
 		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
 		return
 	}
@@ -115,8 +371,15 @@ func stateAPIHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func appsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	appsMu.Lock()
+	running := map[string]*app{}
+	for key, app := range apps {
+		running[key] = app
+	}
+	appsMu.Unlock()
+
 	content, err := json.MarshalIndent(map[string]interface{}{
-		"apps": apps,
+		"apps": running,
 	}, "", "  ")
 	if err != nil {
 		log.Println("[app]", "internal server error", err)
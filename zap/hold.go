@@ -0,0 +1,82 @@
+package zap
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/moomerman/zap/adapter"
+)
+
+// defaultHoldTimeout and defaultHoldQueue are used when an app holds
+// requests without setting hold_timeout or hold_queue
+const (
+	defaultHoldTimeout = 30 * time.Second
+	defaultHoldQueue   = 100
+)
+
+// retryAfter is the Retry-After sent to clients when the app isn't running
+const retryAfter = "5"
+
+// holdTimeout returns how long a request is held waiting for the app to run
+func (c *AppConfig) holdTimeout() time.Duration {
+	if c.HoldTimeout != 0 {
+		return c.HoldTimeout
+	}
+	return defaultHoldTimeout
+}
+
+// holdQueue returns how many requests can be held at once
+func (c *AppConfig) holdQueue() int {
+	if c.HoldQueue != 0 {
+		return c.HoldQueue
+	}
+	return defaultHoldQueue
+}
+
+// hold waits until the app is running, returning false if the queue is
+// full, the app fails to start, it times out or the request is cancelled
+func (a *app) hold(ctx context.Context) bool {
+	a.holdMu.Lock()
+	if a.held >= a.config().holdQueue() {
+		a.holdMu.Unlock()
+		return false
+	}
+	a.held++
+	a.holdMu.Unlock()
+
+	defer func() {
+		a.holdMu.Lock()
+		a.held--
+		a.holdMu.Unlock()
+	}()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(a.config().holdTimeout())
+
+	for {
+		switch a.Status() {
+		case string(adapter.StatusRunning), string(adapter.StatusUnhealthy):
+			return true
+		case string(adapter.StatusStarting), statusWaiting:
+		default:
+			return false
+		}
+
+		select {
+		case <-ticker.C:
+		case <-timeout:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// acceptsHTML reports whether the request comes from a browser that can
+// show the status page
+func acceptsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
@@ -0,0 +1,68 @@
+package zap
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moomerman/zap/adapter"
+)
+
+// bootingAdapter is an adapter whose status is set by the test
+type bootingAdapter struct {
+	mu     sync.Mutex
+	status adapter.Status
+}
+
+func (b *bootingAdapter) Start() error                { return nil }
+func (b *bootingAdapter) Stop(reason error) error     { return nil }
+func (b *bootingAdapter) WriteLog(w io.Writer)        {}
+func (b *bootingAdapter) LogLines() []adapter.LogLine { return nil }
+
+func (b *bootingAdapter) Status() adapter.Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.status
+}
+
+func (b *bootingAdapter) setStatus(status adapter.Status) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.status = status
+}
+
+func (b *bootingAdapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	io.WriteString(w, "hello")
+}
+
+func TestHoldRequests(t *testing.T) {
+	adpt := &bootingAdapter{status: adapter.StatusStarting}
+	app := &app{Config: &AppConfig{Host: "moo.test", HoldRequests: true, HoldTimeout: time.Second}, Adapter: adpt}
+
+	serve := func(accept string) *httptest.ResponseRecorder {
+		ctx := context.WithValue(context.Background(), appKey, app)
+		req := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+		req.Header.Set("Accept", accept)
+		rr := httptest.NewRecorder()
+		appHandler(rr, req)
+		return rr
+	}
+
+	time.AfterFunc(200*time.Millisecond, func() { adpt.setStatus(adapter.StatusRunning) })
+	if rr := serve("application/json"); rr.Code != http.StatusOK || rr.Body.String() != "hello" {
+		t.Fatal("expected the held request to be proxied, got", rr.Code, rr.Body.String())
+	}
+
+	adpt.setStatus(adapter.StatusError)
+	rr := serve("application/json")
+	if rr.Code != http.StatusServiceUnavailable || rr.Header().Get("Retry-After") == "" {
+		t.Error("expected 503 with Retry-After, got", rr.Code, rr.Header())
+	}
+	if rr := serve("text/html,application/xhtml+xml"); rr.Code != http.StatusAccepted {
+		t.Error("expected the status page for browsers, got", rr.Code)
+	}
+}
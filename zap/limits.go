@@ -0,0 +1,76 @@
+package zap
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/moomerman/zap/adapter/server"
+	"github.com/vektra/errors"
+)
+
+// limits returns the resource limits for the app processes
+func (c *AppConfig) limits() (server.Limits, error) {
+	limits := server.Limits{MaxOpenFiles: c.MaxOpenFiles, Nice: c.Nice}
+
+	if c.MaxMemory != "" {
+		size, err := parseSize(c.MaxMemory)
+		if err != nil {
+			return limits, errors.Context(err, "invalid max_memory")
+		}
+		limits.MaxMemory = size
+	}
+
+	if c.CPUQuota != "" {
+		quota, err := parseCPUQuota(c.CPUQuota)
+		if err != nil {
+			return limits, errors.Context(err, "invalid cpu_quota")
+		}
+		limits.CPUQuota = quota
+	}
+
+	if c.Nice < -20 || c.Nice > 19 {
+		return limits, errors.Format("invalid nice %d, must be between -20 and 19", c.Nice)
+	}
+
+	return limits, nil
+}
+
+// parseSize parses a size in bytes with an optional K, M, G or T suffix,
+// eg. 512M or 1.5G
+func parseSize(size string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+
+	multiplier := float64(1)
+	if n := len(s); n > 0 {
+		if i := strings.IndexByte("KMGT", s[n-1]); i >= 0 {
+			for ; i >= 0; i-- {
+				multiplier *= 1024
+			}
+			s = s[:n-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || value <= 0 {
+		return 0, errors.Format("%q is not a size", size)
+	}
+	return int64(value * multiplier), nil
+}
+
+// parseCPUQuota parses a number of CPUs (eg. 1.5) or a percentage of one
+// CPU (eg. 50%)
+func parseCPUQuota(quota string) (float64, error) {
+	s := strings.TrimSpace(quota)
+	divisor := float64(1)
+	if strings.HasSuffix(s, "%") {
+		s = strings.TrimSuffix(s, "%")
+		divisor = 100
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || value <= 0 {
+		return 0, errors.Format("%q is not a number of CPUs or a percentage", quota)
+	}
+	return value / divisor, nil
+}
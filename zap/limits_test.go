@@ -0,0 +1,32 @@
+package zap
+
+import (
+	"testing"
+
+	"github.com/moomerman/zap/adapter/server"
+)
+
+func TestLimits(t *testing.T) {
+	config := &AppConfig{MaxMemory: "1.5G", CPUQuota: "50%", MaxOpenFiles: 1024, Nice: 10}
+	limits, err := config.limits()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := server.Limits{MaxMemory: 1536 * 1024 * 1024, CPUQuota: 0.5, MaxOpenFiles: 1024, Nice: 10}
+	if limits != expected {
+		t.Error("expected", expected, "got", limits)
+	}
+
+	for _, size := range []string{"512M", "512MB", "512Mi", "536870912"} {
+		if n, err := parseSize(size); err != nil || n != 512*1024*1024 {
+			t.Error("unexpected size for", size, n, err)
+		}
+	}
+
+	for _, config := range []*AppConfig{{MaxMemory: "lots"}, {CPUQuota: "-1"}, {Nice: 40}} {
+		if _, err := config.limits(); err == nil {
+			t.Error("expected an error for", config)
+		}
+	}
+}
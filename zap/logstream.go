@@ -0,0 +1,49 @@
+package zap
+
+import (
+	"sync"
+
+	"github.com/moomerman/zap/adapter"
+)
+
+// logStream fans out new log lines from the app's adapters to subscribers,
+// it outlives any single adapter so followers survive restarts
+type logStream struct {
+	mu          sync.Mutex
+	subscribers map[chan adapter.LogLine]struct{}
+}
+
+// Subscribe returns a channel that receives every new log line
+func (s *logStream) Subscribe() chan adapter.LogLine {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.subscribers == nil {
+		s.subscribers = make(map[chan adapter.LogLine]struct{})
+	}
+
+	c := make(chan adapter.LogLine, 256)
+	s.subscribers[c] = struct{}{}
+	return c
+}
+
+// Unsubscribe stops sending log lines to the channel
+func (s *logStream) Unsubscribe(c chan adapter.LogLine) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subscribers, c)
+}
+
+// Publish sends the line to every subscriber, slow subscribers miss lines
+// rather than holding up the app
+func (s *logStream) Publish(line adapter.LogLine) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c := range s.subscribers {
+		select {
+		case c <- line:
+		default:
+		}
+	}
+}
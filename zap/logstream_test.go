@@ -0,0 +1,90 @@
+package zap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moomerman/zap/adapter"
+	"golang.org/x/net/websocket"
+)
+
+func TestLogStreamAPI(t *testing.T) {
+	app := &app{Config: &AppConfig{Host: "moo.test"}}
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), appKey, app))
+	req := httptest.NewRequest("GET", "/zap/api/log/stream?filter=error&stream=stdout", nil).WithContext(ctx)
+	rr := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		logStreamAPIHandler(rr, req)
+		close(done)
+	}()
+
+	// wait for the handler to subscribe
+	for i := 0; i < 100; i++ {
+		app.logs.mu.Lock()
+		subscribed := len(app.logs.subscribers) > 0
+		app.logs.mu.Unlock()
+		if subscribed {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	app.logs.Publish(adapter.LogLine{Stream: adapter.StreamStdout, Text: "compiled successfully\n"})
+	app.logs.Publish(adapter.LogLine{Stream: adapter.StreamStdout, Text: "an error occurred\n"})
+	app.logs.Publish(adapter.LogLine{Stream: adapter.StreamStderr, Text: "another error occurred\n"})
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+
+	if rr.Code != http.StatusOK {
+		t.Fatal("unexpected status", rr.Code)
+	}
+	if body := rr.Body.String(); body != "event: stdout\ndata: an error occurred\n\n" {
+		t.Error("unexpected stream body", strings.TrimSpace(body))
+	}
+}
+
+func TestLogStreamOrigin(t *testing.T) {
+	app := &app{Config: &AppConfig{Host: "moo.test"}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logStreamAPIHandler(w, r.WithContext(context.WithValue(r.Context(), appKey, app)))
+	}))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/zap/api/log/stream"
+
+	if ws, err := websocket.Dial(url, "", "http://evil.example"); err == nil {
+		ws.Close()
+		t.Error("expected a page on another host to be refused")
+	}
+
+	ws, err := websocket.Dial(url, "", server.URL)
+	if err != nil {
+		t.Fatal("expected a page on the same host to be accepted", err)
+	}
+	ws.Close()
+
+	tests := map[string]bool{
+		"":                         true,
+		"http://moo.test":          true,
+		"https://MOO.test":         true,
+		"http://moo.test.evil.com": false,
+		"null":                     false,
+	}
+	for origin, allowed := range tests {
+		req := httptest.NewRequest("GET", "http://moo.test/zap/api/log/stream", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if err := checkOrigin(req); (err == nil) != allowed {
+			t.Error(origin, "expected allowed", allowed, "got", err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package zap
+
+import (
+	"fmt"
+
+	"github.com/moomerman/zap/adapter"
+)
+
+// recentMetrics is how many resource samples are shown on the status page
+const recentMetrics = 12
+
+// metricsSample formats a resource sample for the status page
+type metricsSample struct {
+	adapter.ProcessMetrics
+}
+
+// CPU returns the CPU usage since the previous sample
+func (m metricsSample) CPU() string {
+	return fmt.Sprintf("%.1f%%", m.CPUPercent)
+}
+
+// Memory returns the resident memory in a readable unit
+func (m metricsSample) Memory() string {
+	const unit = 1024
+	if m.RSS < unit {
+		return fmt.Sprintf("%dB", m.RSS)
+	}
+	div, exp := int64(unit), 0
+	for n := m.RSS / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(m.RSS)/float64(div), "KMGT"[exp])
+}
+
+// Metrics returns the recent resource samples for the app process tree,
+// newest first, if the adapter collects them
+func (a *app) Metrics() []metricsSample {
+	m, ok := a.Adapter.(interface {
+		Metrics() []adapter.ProcessMetrics
+	})
+	if !ok {
+		return nil
+	}
+
+	samples := m.Metrics()
+	recent := []metricsSample{}
+	for i := len(samples) - 1; i >= 0 && len(recent) < recentMetrics; i-- {
+		recent = append(recent, metricsSample{samples[i]})
+	}
+	return recent
+}
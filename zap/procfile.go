@@ -0,0 +1,78 @@
+package zap
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/moomerman/zap/adapter/server"
+	"github.com/puma/puma-dev/homedir"
+	"github.com/vektra/errors"
+)
+
+// procfileDev is the Procfile that is used automatically when an app has a
+// dir but no command
+const procfileDev = "Procfile.dev"
+
+var procfileLine = regexp.MustCompile(`^([A-Za-z0-9_-]+):\s*(.+)$`)
+
+// procfilePath returns the Procfile to run for the app, if any
+func (c *AppConfig) procfilePath() string {
+	if c.Procfile != "" {
+		path := homedir.MustExpand(c.Procfile)
+		if !filepath.IsAbs(path) && c.Dir != "" {
+			path = filepath.Join(homedir.MustExpand(c.Dir), path)
+		}
+		return path
+	}
+
+	if c.Command != "" || c.Dir == "" {
+		return ""
+	}
+
+	path := filepath.Join(homedir.MustExpand(c.Dir), procfileDev)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// readProcfile returns the processes in the Procfile in the order they are
+// declared
+func readProcfile(path string) ([]server.Process, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Context(err, "reading procfile")
+	}
+	defer file.Close()
+
+	processes := []server.Process{}
+	names := map[string]bool{}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		match := procfileLine.FindStringSubmatch(line)
+		if match == nil {
+			return nil, errors.Format("invalid procfile line %q", line)
+		}
+		if names[match[1]] {
+			return nil, errors.Format("duplicate procfile process %q", match[1])
+		}
+		names[match[1]] = true
+
+		processes = append(processes, server.Process{Name: match[1], Command: strings.TrimSpace(match[2])})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Context(err, "reading procfile")
+	}
+
+	return processes, nil
+}
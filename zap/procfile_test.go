@@ -0,0 +1,76 @@
+package zap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadProcfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zap-procfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	procfile := "# processes\nweb: bin/rails server -p $PORT\n\nworker: bundle exec sidekiq\ncss:bin/rails tailwindcss:watch\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, procfileDev), []byte(procfile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &AppConfig{Dir: dir}
+	path := config.procfilePath()
+	if path != filepath.Join(dir, procfileDev) {
+		t.Fatal("expected Procfile.dev to be detected, got", path)
+	}
+
+	processes, err := readProcfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{"web", "worker", "css"}
+	if len(processes) != len(names) {
+		t.Fatal("expected", len(names), "processes, got", processes)
+	}
+	for i, name := range names {
+		if processes[i].Name != name {
+			t.Error("expected", name, "got", processes[i].Name)
+		}
+	}
+	if processes[2].Command != "bin/rails tailwindcss:watch" {
+		t.Error("unexpected command", processes[2].Command)
+	}
+
+	config.Command = "bin/rails server"
+	if path := config.procfilePath(); path != "" {
+		t.Error("expected no procfile when a command is set, got", path)
+	}
+}
+
+func TestProcfileWithCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zap-procfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	procfile := "web: bin/rails server -p $PORT\nworker: bundle exec sidekiq\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "Procfile"), []byte(procfile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &AppConfig{Host: "moo.test", Dir: dir, Command: "bin/dev", Procfile: "Procfile"}
+	serverConfig, err := getServerConfig(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(serverConfig.Processes) != 1 || serverConfig.Processes[0].Name != "worker" {
+		t.Error("expected only the worker to run alongside the command, got", serverConfig.Processes)
+	}
+	if serverConfig.ShellCommand != "exec bin/dev # %s %s" {
+		t.Error("expected the command to be the web process, got", serverConfig.ShellCommand)
+	}
+}
@@ -0,0 +1,301 @@
+package zap
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/moomerman/zap/adapter"
+	"github.com/moomerman/zap/adapter/proxy"
+	"github.com/moomerman/zap/adapter/server"
+	"github.com/moomerman/zap/adapter/static"
+	"github.com/puma/puma-dev/homedir"
+	"github.com/vektra/errors"
+)
+
+// RouteConfig is a routes entry, requests under the path prefix go to the
+// proxy URL, the static dir or the command run in dir instead of the app
+type RouteConfig struct {
+	Path    string            `yaml:"path"`
+	Strip   bool              `yaml:"strip" json:",omitempty"`
+	Proxy   string            `yaml:"proxy" json:",omitempty"`
+	Dir     string            `yaml:"dir" json:",omitempty"`
+	Command string            `yaml:"command" json:",omitempty"`
+	Env     map[string]string `yaml:"env" json:",omitempty"`
+}
+
+// route sends the requests under a path prefix to a backend of its own
+type route struct {
+	Config  RouteConfig
+	Adapter adapter.Adapter
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// RouteStatus is the backend and status of a route
+type RouteStatus struct {
+	Path   string
+	Target string
+	Status string
+}
+
+// name is used to tell the route apart in log file names
+func (c RouteConfig) name() string {
+	name := strings.Replace(strings.Trim(c.Path, "/"), "/", "-", -1)
+	if name == "" {
+		return "root"
+	}
+	return name
+}
+
+// target describes where the route sends requests
+func (c RouteConfig) target() string {
+	switch {
+	case c.Proxy != "":
+		return c.Proxy
+	case c.Command != "":
+		return c.Command
+	}
+	return c.Dir
+}
+
+// prefix is the path without a trailing slash so /api and /api/ match the
+// same requests
+func (c RouteConfig) prefix() string {
+	return strings.TrimRight(c.Path, "/")
+}
+
+// matches reports whether the route handles the request path, a prefix
+// only matches whole path segments
+func (c RouteConfig) matches(path string) bool {
+	prefix := c.prefix()
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// validateRoutes checks each route has a path and exactly one backend
+func validateRoutes(routes []RouteConfig) error {
+	paths := map[string]bool{}
+	for _, r := range routes {
+		if !strings.HasPrefix(r.Path, "/") {
+			return errors.Format("routes: path %q must start with /", r.Path)
+		}
+		if paths[r.prefix()] {
+			return errors.Format("routes: path %q is routed twice", r.Path)
+		}
+		paths[r.prefix()] = true
+
+		if r.Proxy != "" && (r.Dir != "" || r.Command != "") {
+			return errors.Format("routes: %s has both a proxy and a dir or command", r.Path)
+		}
+		if r.Proxy == "" && r.Dir == "" && r.Command == "" {
+			return errors.Format("routes: %s needs a proxy, dir or command", r.Path)
+		}
+		if r.Proxy != "" {
+			if _, err := url.Parse(r.Proxy); err != nil {
+				return errors.Context(err, "routes: invalid proxy for "+r.Path)
+			}
+		}
+	}
+	return nil
+}
+
+// newRoutes creates the adapters for the routes of the app, longest path
+// first so the most specific route matches
+func newRoutes(config *AppConfig) ([]*route, error) {
+	if err := validateRoutes(config.Routes); err != nil {
+		return nil, err
+	}
+
+	routes := []*route{}
+	for _, rc := range config.Routes {
+		adpt, err := newRouteAdapter(config, rc)
+		if err != nil {
+			return nil, errors.Context(err, "route "+rc.Path)
+		}
+		routes = append(routes, &route{Config: rc, Adapter: adpt})
+	}
+
+	// insertion sort keeps the config order for equal lengths
+	for i := 1; i < len(routes); i++ {
+		for j := i; j > 0 && len(routes[j].Config.prefix()) > len(routes[j-1].Config.prefix()); j-- {
+			routes[j], routes[j-1] = routes[j-1], routes[j]
+		}
+	}
+	return routes, nil
+}
+
+// newRouteAdapter returns the adapter for a route, a command is run with the
+// app run settings in the route dir, or the app dir if it has none
+func newRouteAdapter(config *AppConfig, rc RouteConfig) (adapter.Adapter, error) {
+	if rc.Proxy != "" {
+		return proxy.New(config.Host, rc.Proxy)
+	}
+
+	dir := config.Dir
+	if rc.Dir != "" {
+		dir = homedir.MustExpand(rc.Dir)
+		if !filepath.IsAbs(dir) && config.Dir != "" {
+			dir = filepath.Join(homedir.MustExpand(config.Dir), dir)
+		}
+	}
+
+	if rc.Command == "" {
+		return static.New(dir)
+	}
+
+	routeConfig := &AppConfig{
+		Scheme:      "http",
+		Host:        config.Host,
+		Port:        config.Port,
+		Dir:         dir,
+		Command:     rc.Command,
+		Env:         rc.Env,
+		Run:         config.Run,
+		Shell:       config.Shell,
+		ShellArgs:   config.ShellArgs,
+		BootTimeout: config.BootTimeout,
+		StopTimeout: config.StopTimeout,
+	}
+
+	serverConfig, err := getServerConfig(routeConfig, nil)
+	if err != nil {
+		return nil, err
+	}
+	// route processes aren't adopted so their output is never spooled
+	serverConfig.SpoolDir = ""
+	if serverConfig.LogFile != "" {
+		serverConfig.LogFile = strings.TrimSuffix(serverConfig.LogFile, ".log") + "." + rc.name() + ".log"
+	}
+	return server.New(serverConfig), nil
+}
+
+// findRoute returns the route for the request path, if any
+func (a *app) findRoute(path string) *route {
+	a.routesMu.Lock()
+	defer a.routesMu.Unlock()
+
+	for _, r := range a.routes {
+		if r.Config.matches(path) {
+			return r
+		}
+	}
+	return nil
+}
+
+// startRoutes starts the routes that aren't running
+func (a *app) startRoutes() {
+	a.routesMu.Lock()
+	routes := a.routes
+	a.routesMu.Unlock()
+
+	for _, r := range routes {
+		if err := r.Start(); err != nil {
+			log.Println("[app]", a.config().Host, "error starting route", r.Config.Path, err)
+		}
+	}
+}
+
+// stopRoutes stops every route and removes them, a request that found a
+// route before it was removed can't start it again
+func (a *app) stopRoutes(reason error) {
+	a.routesMu.Lock()
+	routes := a.routes
+	a.routes = nil
+	a.routesMu.Unlock()
+
+	for _, r := range routes {
+		if err := r.close(reason); err != nil {
+			log.Println("[app]", a.config().Host, "error stopping route", r.Config.Path, err)
+		}
+	}
+}
+
+// RouteStatus returns the status of each route
+func (a *app) RouteStatus() []RouteStatus {
+	a.routesMu.Lock()
+	defer a.routesMu.Unlock()
+
+	statuses := []RouteStatus{}
+	for _, r := range a.routes {
+		statuses = append(statuses, RouteStatus{
+			Path:   r.Config.Path,
+			Target: r.Config.target(),
+			Status: string(r.Adapter.Status()),
+		})
+	}
+	return statuses
+}
+
+// Start starts the route backend unless it's already running or the route
+// has been removed
+func (r *route) Start() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return errors.New("route has been removed")
+	}
+
+	switch r.Adapter.Status() {
+	case adapter.StatusRunning, adapter.StatusStarting, adapter.StatusUnhealthy:
+		return nil
+	}
+	return r.Adapter.Start()
+}
+
+// close stops the route backend for good
+func (r *route) close(reason error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	return r.Adapter.Stop(reason)
+}
+
+// ServeHTTP sends the request to the route backend with the prefix stripped
+// if the route asks for it, starting the backend again if it has stopped
+func (r *route) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.Adapter.Status() == adapter.StatusStopped {
+		if err := r.Start(); err != nil {
+			log.Println("[app]", req.Host, "error starting route", r.Config.Path, err)
+		}
+	}
+
+	switch r.Adapter.Status() {
+	case adapter.StatusRunning, adapter.StatusUnhealthy:
+	default:
+		w.Header().Set("Retry-After", retryAfter)
+		http.Error(w, "503 Service Unavailable: route "+r.Config.Path+" is "+string(r.Adapter.Status()), http.StatusServiceUnavailable)
+		return
+	}
+
+	if r.Config.Strip {
+		req = stripPrefix(req, r.Config.prefix())
+	}
+	r.Adapter.ServeHTTP(w, req)
+}
+
+// stripPrefix returns a copy of the request with the prefix removed from
+// its path
+func stripPrefix(r *http.Request, prefix string) *http.Request {
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+
+	r2.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
+	if r2.URL.Path == "" {
+		r2.URL.Path = "/"
+	}
+	if r.URL.RawPath != "" {
+		r2.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, prefix)
+		if r2.URL.RawPath == "" {
+			r2.URL.RawPath = "/"
+		}
+	}
+	return r2
+}
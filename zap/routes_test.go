@@ -0,0 +1,106 @@
+package zap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFindRoute(t *testing.T) {
+	config := &AppConfig{Host: "moo.test", Routes: []RouteConfig{
+		{Path: "/api", Proxy: "http://127.0.0.1:4000"},
+		{Path: "/api/admin/", Proxy: "http://127.0.0.1:4001"},
+		{Path: "/assets", Dir: "/tmp"},
+	}}
+	routes, err := newRoutes(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &app{Config: config, routes: routes}
+
+	tests := map[string]string{
+		"/api":            "/api",
+		"/api/users":      "/api",
+		"/api/admin":      "/api/admin/",
+		"/api/admin/jobs": "/api/admin/",
+		"/apis":           "",
+		"/assets/app.js":  "/assets",
+		"/":               "",
+	}
+
+	for path, expected := range tests {
+		r := a.findRoute(path)
+		if r == nil {
+			if expected != "" {
+				t.Error(path, "expected", expected, "got no route")
+			}
+			continue
+		}
+		if r.Config.Path != expected {
+			t.Error(path, "expected", expected, "got", r.Config.Path)
+		}
+	}
+}
+
+func TestStripPrefix(t *testing.T) {
+	tests := map[string]string{
+		"/api/users?page=2": "/users",
+		"/api":              "/",
+		"/api/":             "/",
+	}
+
+	for target, expected := range tests {
+		r := httptest.NewRequest("GET", target, nil)
+		stripped := stripPrefix(r, "/api")
+		if stripped.URL.Path != expected {
+			t.Error(target, "expected", expected, "got", stripped.URL.Path)
+		}
+		if stripped.URL.RawQuery != r.URL.RawQuery || r.URL.Path == stripped.URL.Path {
+			t.Error(target, "expected a copy with the query kept, got", stripped.URL)
+		}
+	}
+}
+
+func TestValidateRoutes(t *testing.T) {
+	invalid := [][]RouteConfig{
+		{{Path: "api", Proxy: "http://127.0.0.1:4000"}},
+		{{Path: "/api"}},
+		{{Path: "/api", Proxy: "http://127.0.0.1:4000", Command: "bin/api"}},
+		{{Path: "/api", Dir: "public"}, {Path: "/api/", Dir: "assets"}},
+	}
+
+	for _, routes := range invalid {
+		if err := validateRoutes(routes); err == nil {
+			t.Error("expected an error for", routes)
+		}
+	}
+
+	if err := validateRoutes([]RouteConfig{{Path: "/api", Command: "bin/api -port %s", Strip: true}}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStoppedRouteStaysStopped(t *testing.T) {
+	config := &AppConfig{Host: "moo.test", Routes: []RouteConfig{
+		{Path: "/assets", Dir: "/tmp"},
+	}}
+	routes, err := newRoutes(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &app{Config: config, routes: routes}
+	a.startRoutes()
+
+	r := a.findRoute("/assets/app.js")
+	a.stopRoutes(nil)
+
+	if err := r.Start(); err == nil {
+		t.Error("expected a removed route to refuse to start")
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/assets/app.js", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Error("expected 503 from a removed route, got", w.Code)
+	}
+}
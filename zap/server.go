@@ -6,8 +6,10 @@ import (
 	"log"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/moomerman/zap/adapter/server"
 	"github.com/moomerman/zap/cert"
 	"golang.org/x/net/http2"
 )
@@ -16,15 +18,37 @@ import (
 type Server struct {
 	HTTPAddr  string
 	HTTPSAddr string
+	Config    *Config
 
-	http  *http.Server
-	https *http.Server
+	http    *http.Server
+	https   *http.Server
+	watcher *configWatcher
+	state   *stateSaver
+
+	stopping int32
+	stopped  chan struct{}
 }
 
 // Serve starts the HTTP servers
 func (s *Server) Serve() {
+	if s.Config != nil {
+		globalConfig = s.Config
+	}
+
 	s.http = createHTTPServer()
 	s.https = createHTTPSServer()
+	s.watcher = newConfigWatcher(appsPath)
+	s.state = newStateSaver()
+	s.stopped = make(chan struct{})
+
+	if from, to, err := globalConfig.portRange(); err == nil {
+		server.SetPortRange(from, to)
+	}
+
+	recoverOrphans()
+
+	go s.watcher.Watch()
+	go s.state.Run()
 
 	var wg sync.WaitGroup
 	wg.Add(2)
@@ -44,15 +68,34 @@ func (s *Server) Serve() {
 	}()
 
 	wg.Wait()
+
+	// the servers return as soon as Stop begins, wait for the apps too
+	if atomic.LoadInt32(&s.stopping) == 1 {
+		<-s.stopped
+	}
 }
 
-// Stop gracefully stops the HTTP and HTTPS servers
+// Stop gracefully stops the HTTP and HTTPS servers so no more requests come
+// in, then stops every app. The state file keeps every app until it has
+// stopped so a new zapd can find any that are left
 func (s *Server) Stop() {
+	atomic.StoreInt32(&s.stopping, 1)
+	defer close(s.stopped)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	s.watcher.Stop()
 	s.http.Shutdown(ctx)
 	s.https.Shutdown(ctx)
+
+	// saved with every app before any are stopped and not again until they
+	// all have
+	s.state.Stop()
+	remaining := stopApps(globalConfig.ShutdownTimeout)
+	if err := writeState(remaining); err != nil {
+		log.Println("[state]", "error saving state", err)
+	}
 }
 
 func createHTTPServer() *http.Server {
@@ -70,6 +113,7 @@ func createHTTPSServer() *http.Server {
 	// Maybe have a zapHandler that checks for localhost and then delegates requests
 	mux.HandleFunc("/zap/api/apps", appsAPIHandler)
 	mux.HandleFunc("/zap/api/log", findAppHandler(logAPIHandler))
+	mux.HandleFunc("/zap/api/log/stream", findAppHandler(logStreamAPIHandler))
 	mux.HandleFunc("/zap/api/state", findAppHandler(stateAPIHandler))
 	mux.HandleFunc("/zap/ngrok/start", findAppHandler(startNgrokHandler))
 	mux.HandleFunc("/zap/ngrok", findAppHandler(ngrokHandler))
@@ -78,7 +122,7 @@ func createHTTPSServer() *http.Server {
 	mux.HandleFunc("/zap", findAppHandler(statusHandler))
 	mux.HandleFunc("/", findAppHandler(appHandler))
 
-	cache, err := cert.NewCache()
+	cache, err := cert.NewCacheWithSize(globalConfig.CertCacheSize)
 	if err != nil {
 		log.Fatal("[zap] unable to create new cert cache", err)
 	}
@@ -0,0 +1,102 @@
+package zap
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/moomerman/zap/adapter/server"
+)
+
+// stopApps stops every running app and its ngrok tunnel in parallel. The
+// processes of any app still stopping once the timeout has passed are killed
+// and returned, so they're kept in the state file in case they survive
+func stopApps(timeout time.Duration) []appState {
+	// taken before the apps are stopped as they're removed from apps first
+	states := runningState()
+
+	appsMu.Lock()
+	running := []*app{}
+	for _, app := range apps {
+		running = append(running, app)
+	}
+	appsMu.Unlock()
+
+	if len(running) == 0 {
+		return nil
+	}
+
+	log.Println("[zap] stopping", len(running), "apps")
+
+	var mu sync.Mutex
+	stopped := map[*app]bool{}
+
+	var wg sync.WaitGroup
+	for _, a := range running {
+		wg.Add(1)
+		go func(a *app) {
+			defer wg.Done()
+			a.shutdown()
+
+			mu.Lock()
+			stopped[a] = true
+			mu.Unlock()
+		}(a)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Println("[zap] all apps stopped")
+		return nil
+	case <-time.After(timeout):
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	remaining := []appState{}
+	for _, a := range running {
+		if stopped[a] {
+			continue
+		}
+		for _, state := range states {
+			if state.Key != a.config().Key {
+				continue
+			}
+			log.Println("[zap]", state.Host, "still stopping after", timeout, "killing", state.Pid)
+			for _, pgid := range append([]int{state.Pid}, state.Workers...) {
+				if err := server.Kill(pgid); err != nil {
+					log.Println("[zap]", state.Host, "error killing", pgid, err)
+				}
+			}
+			remaining = append(remaining, state)
+		}
+	}
+	return remaining
+}
+
+// shutdown stops the app because zapd is exiting, reporting any processes
+// that had to be killed
+func (a *app) shutdown() {
+	if a.Ngrok != nil {
+		a.Ngrok.Stop()
+	}
+
+	if err := a.Stop("zapd shutting down", nil); err != nil {
+		log.Println("[app]", a.config().Host, "error stopping", err)
+	}
+
+	if k, ok := a.Adapter.(interface {
+		Killed() []int
+	}); ok {
+		if killed := k.Killed(); len(killed) > 0 {
+			log.Println("[app]", a.config().Host, "force killed", killed)
+		}
+	}
+}
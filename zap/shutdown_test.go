@@ -0,0 +1,97 @@
+//go:build !windows
+// +build !windows
+
+package zap
+
+import (
+	"io"
+	"net/http"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/moomerman/zap/adapter"
+	"github.com/moomerman/zap/adapter/server"
+)
+
+// slowAdapter takes until stopped is closed, or delay has passed, to stop
+type slowAdapter struct {
+	delay   time.Duration
+	stopped chan struct{}
+	pid     int
+}
+
+func (s *slowAdapter) Start() error { return nil }
+
+func (s *slowAdapter) Stop(reason error) error {
+	select {
+	case <-time.After(s.delay):
+	case <-s.stopped:
+	}
+	return nil
+}
+
+func (s *slowAdapter) Status() adapter.Status                           { return adapter.StatusRunning }
+func (s *slowAdapter) WriteLog(w io.Writer)                             {}
+func (s *slowAdapter) LogLines() []adapter.LogLine                      { return nil }
+func (s *slowAdapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {}
+func (s *slowAdapter) RunState() server.RunState                        { return server.RunState{Pid: s.pid} }
+
+func setApps(adapters map[string]adapter.Adapter) {
+	appsMu.Lock()
+	defer appsMu.Unlock()
+	apps = map[string]*app{}
+	for host, adpt := range adapters {
+		apps[host] = &app{Config: &AppConfig{Host: host, Key: host}, Adapter: adpt}
+	}
+}
+
+func TestStopAppsInParallel(t *testing.T) {
+	stopped := make(chan struct{})
+	defer close(stopped)
+
+	setApps(map[string]adapter.Adapter{
+		"one.test": &slowAdapter{delay: 300 * time.Millisecond, stopped: stopped},
+		"two.test": &slowAdapter{delay: 300 * time.Millisecond, stopped: stopped},
+	})
+	defer setApps(nil)
+
+	start := time.Now()
+	if remaining := stopApps(5 * time.Second); len(remaining) != 0 {
+		t.Error("expected every app to stop, got", remaining)
+	}
+	if elapsed := time.Since(start); elapsed > 550*time.Millisecond {
+		t.Error("expected the apps to stop in parallel, took", elapsed)
+	}
+}
+
+func TestStopAppsDeadline(t *testing.T) {
+	cmd := exec.Command("sleep", "30")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	stopped := make(chan struct{})
+	defer close(stopped)
+
+	setApps(map[string]adapter.Adapter{
+		"slow.test": &slowAdapter{delay: time.Minute, stopped: stopped, pid: cmd.Process.Pid},
+	})
+	defer setApps(nil)
+
+	remaining := stopApps(200 * time.Millisecond)
+	if len(remaining) != 1 || remaining[0].Host != "slow.test" || remaining[0].Pid != cmd.Process.Pid {
+		t.Fatal("expected the app still stopping to be kept in the state, got", remaining)
+	}
+
+	select {
+	case <-exited:
+	case <-time.After(2 * time.Second):
+		cmd.Process.Kill()
+		t.Fatal("expected the process still running after the deadline to be killed")
+	}
+}
@@ -0,0 +1,260 @@
+package zap
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/moomerman/zap/adapter/server"
+	"github.com/puma/puma-dev/homedir"
+	"github.com/vektra/errors"
+)
+
+// orphans settings, what to do with app processes left running by a previous
+// zapd when it starts
+const (
+	orphansReap  = "reap"
+	orphansAdopt = "adopt"
+)
+
+// spoolPath is where app output is written when orphans are adopted and
+// statePath records the running app processes
+const (
+	spoolPath = appsPath + "/run"
+	statePath = spoolPath + "/state.json"
+)
+
+// appState records the processes of a running app
+type appState struct {
+	Host    string
+	Key     string
+	Started time.Time
+	server.RunState
+}
+
+// stateSaver writes the state file periodically so that app processes can be
+// found again if zapd goes away without stopping them
+type stateSaver struct {
+	Interval time.Duration
+
+	done chan struct{}
+}
+
+func newStateSaver() *stateSaver {
+	return &stateSaver{
+		Interval: 2 * time.Second,
+		done:     make(chan struct{}),
+	}
+}
+
+// Run saves the state until Stop is called
+func (s *stateSaver) Run() {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			if err := saveState(); err != nil {
+				log.Println("[state]", "error saving state", err)
+			}
+		}
+	}
+}
+
+// Stop stops saving the state, saving it one last time
+func (s *stateSaver) Stop() {
+	close(s.done)
+	if err := saveState(); err != nil {
+		log.Println("[state]", "error saving state", err)
+	}
+}
+
+// saveState writes the processes of the running apps to the state file
+func saveState() error {
+	return writeState(runningState())
+}
+
+// runningState returns the processes of the running apps
+func runningState() []appState {
+	states := []appState{}
+
+	appsMu.Lock()
+	running := []*app{}
+	for _, app := range apps {
+		running = append(running, app)
+	}
+	appsMu.Unlock()
+
+	for _, app := range running {
+		r, ok := app.Adapter.(interface {
+			RunState() server.RunState
+		})
+		if !ok {
+			continue
+		}
+		if state := r.RunState(); state.Pid != 0 {
+			states = append(states, appState{Host: app.config().Host, Key: app.config().Key, Started: app.Started, RunState: state})
+		}
+	}
+	return states
+}
+
+// lastState is the state last written to lastStatePath, the file is only
+// written again when the state changes
+var (
+	lastState     []byte
+	lastStatePath string
+	lastStateMu   sync.Mutex
+)
+
+// writeState writes the given app processes to the state file unless they
+// are already what it holds
+func writeState(states []appState) error {
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := homedir.MustExpand(statePath)
+
+	lastStateMu.Lock()
+	defer lastStateMu.Unlock()
+	if path == lastStatePath && bytes.Equal(data, lastState) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Context(err, "creating state dir")
+	}
+
+	// write then rename so a crash never leaves a partial file
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return errors.Context(err, "writing state")
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	lastState = data
+	lastStatePath = path
+	return nil
+}
+
+// loadState reads the state file, a missing file is not an error
+func loadState() ([]appState, error) {
+	states := []appState{}
+
+	data, err := ioutil.ReadFile(homedir.MustExpand(statePath))
+	if os.IsNotExist(err) {
+		return states, nil
+	}
+	if err != nil {
+		return nil, errors.Context(err, "reading state")
+	}
+
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, errors.Context(err, "parsing state")
+	}
+	return states, nil
+}
+
+// recoverOrphans deals with the app processes left running by a previous
+// zapd, either adopting them into the apps map or stopping them
+func recoverOrphans() {
+	states, err := loadState()
+	if err != nil {
+		log.Println("[state]", "unable to recover orphans", err)
+		return
+	}
+
+	for _, state := range states {
+		for _, pid := range state.Workers {
+			reapOrphan(state.Host, pid)
+		}
+
+		if globalConfig.Orphans == orphansAdopt && server.OwnedBy(state.Pid, state.Host) {
+			err := adoptOrphan(state)
+			if err == nil {
+				continue
+			}
+			log.Println("[state]", state.Host, "unable to adopt", state.Pid, err)
+		}
+
+		reapOrphan(state.Host, state.Pid)
+	}
+
+	if err := saveState(); err != nil {
+		log.Println("[state]", "error saving state", err)
+	}
+}
+
+// reapOrphan stops the process group if it still belongs to the app
+func reapOrphan(host string, pgid int) {
+	if !server.OwnedBy(pgid, host) {
+		return
+	}
+
+	log.Println("[state]", host, "stopping orphaned process group", pgid)
+	killed, err := server.Reap(pgid, globalConfig.StopTimeout)
+	if err != nil {
+		log.Println("[state]", host, "error stopping orphaned process group", pgid, err)
+	}
+	if len(killed) > 0 {
+		log.Println("[state]", host, "killed", killed)
+	}
+}
+
+// adoptOrphan takes over the running process of an app whose config hasn't
+// changed, the app's Procfile processes are started again
+func adoptOrphan(state appState) error {
+	config, err := getAppConfig(state.Host)
+	if err != nil {
+		return err
+	}
+	if config.Key != state.Key {
+		return errors.New("app config has changed")
+	}
+
+	a := &app{Config: config, Started: state.Started}
+
+	serverConfig, err := getValidServerConfig(config, a.logs.Publish)
+	if err != nil {
+		return err
+	}
+	if serverConfig == nil {
+		return errors.New("app no longer runs a command")
+	}
+	serverConfig.AdoptPid = state.Pid
+	serverConfig.AdoptPort = state.Port
+	serverConfig.AdoptSocket = state.Socket
+
+	a.Adapter = server.New(serverConfig)
+
+	routes, err := newRoutes(config)
+	if err != nil {
+		return errors.Context(err, "could not create routes")
+	}
+	a.routes = routes
+
+	if err := a.Start(); err != nil {
+		return err
+	}
+
+	appsMu.Lock()
+	if apps == nil {
+		apps = make(map[string]*app)
+	}
+	apps[config.Key] = a
+	appsMu.Unlock()
+
+	log.Println("[state]", state.Host, "adopted orphaned process group", state.Pid)
+	return nil
+}
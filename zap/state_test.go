@@ -0,0 +1,66 @@
+package zap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moomerman/zap/adapter/server"
+	"github.com/puma/puma-dev/homedir"
+)
+
+func TestStateFile(t *testing.T) {
+	home, err := ioutil.TempDir("", "zap-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	homedir.DisableCache = true
+	defer func() { homedir.DisableCache = false }()
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", home)
+
+	path := homedir.MustExpand(statePath)
+	states := []appState{{Host: "web.test", Key: "web", RunState: server.RunState{Pid: 100}}}
+	if err := writeState(states); err != nil {
+		t.Fatal(err)
+	}
+
+	// an unchanged state is not written again
+	os.Remove(path)
+	if err := writeState(states); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("expected an unchanged state not to be written")
+	}
+
+	states[0].Pid = 200
+	if err := writeState(states); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 1 || loaded[0].Pid != 200 {
+		t.Fatal("expected the changed state to be written, got", loaded)
+	}
+
+	// an adopted app's config is validated like any other app's
+	dir := filepath.Join(home, ".zap")
+	if err := ioutil.WriteFile(filepath.Join(dir, "web.test"), []byte("command: sleep 30\nwatch: ['[']\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := getAppConfig("web.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = adoptOrphan(appState{Host: "web.test", Key: config.Key, RunState: server.RunState{Pid: 100}})
+	if err == nil || !strings.Contains(err.Error(), "invalid glob") {
+		t.Fatal("expected an invalid watch glob to stop the app being adopted, got", err)
+	}
+}
@@ -92,7 +92,7 @@ func templates502Html() (*asset, error) {
 	return a, nil
 }
 
-var _templatesAppHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x94\x94\xc1\x6e\xd3\x4c\x10\xc7\xef\x7e\x8a\xf9\x7c\xa8\x9c\x28\x9f\x9d\x56\x70\x21\xb1\x05\x45\x15\x01\xa5\x20\xd1\x1c\x10\xb7\xad\x3d\xd9\x2c\xb8\xbb\xdb\xdd\x71\xdb\x10\xf9\x45\xb8\xf1\x6a\x3c\x09\xda\xb5\xe3\xd8\x09\x42\x70\xa8\x54\xef\xec\x7f\x66\xfe\xbf\x99\xcd\x7c\x73\x9e\xfd\xfc\xfe\xe3\x33\xd3\xf0\x3f\xec\x76\x10\xbf\x56\x72\x2d\x78\xbc\x50\x96\xa0\xae\xe7\xc9\xe6\x3c\x0b\x82\xb9\xce\x6e\x88\x51\x65\x5f\xc0\xdc\x6a\x26\x41\x14\x69\x68\xfd\x49\x98\x39\x55\x13\xf5\x02\x17\xcf\xe6\x89\xf6\x32\x83\xfe\x6a\xa9\xb8\xbf\x27\xd6\x80\xf7\xdd\xed\xd0\x54\x52\x0a\xc9\x43\xa8\x6b\x97\xe4\x55\xc1\x34\xa1\x89\x2f\x95\xa2\xa5\xe2\xcd\x29\x96\x16\xdb\xf8\x52\xf1\x15\x13\x65\x7b\x2e\x0b\x5f\x4e\x1b\x74\x95\x6c\x6e\x84\x26\xa0\xad\xc6\x34\x24\x7c\xa2\xe4\x0b\x7b\x60\xcd\x69\x98\x05\x00\xc9\x78\x0c\x2f\x5d\x78\x67\xc9\x08\xc9\x6b\x18\x8f\x93\x00\xe0\x81\x19\x68\x9c\x40\x0a\xe1\xc0\x4b\x38\x0b\x02\x80\x75\x25\x73\x12\x4a\xc2\x1a\x29\xdf\x2c\x15\x8f\x46\xb0\x0b\x00\xc0\xb9\x89\x5a\xe9\x7f\xe9\xc1\xcd\x3e\x0c\x60\x91\x56\xe2\x0e\x55\x45\x51\x97\xc5\xa9\x81\x23\x45\x61\xf2\x8d\xe9\x84\x69\x91\x38\x3a\x13\xa8\x74\xc1\x08\x97\x8a\x8f\x66\x50\x4f\xe0\xf9\x74\x3a\x9a\xf9\x3c\x75\xe0\xfe\xfa\x9d\x74\x57\xa3\x82\x11\xdb\xd7\x2b\x54\x5e\xdd\xa1\xa4\x98\x23\x5d\x95\xe8\xfe\xbd\xdc\xbe\x2d\x22\x4f\x7f\x14\x0b\x29\xd1\x2c\x56\xd7\x4b\x48\xc1\xc9\x66\xc7\x1e\xd2\x14\xdc\x4c\x0d\x0d\x4d\x3c\x0a\x59\xa8\xc7\xd8\xe6\x46\x95\xe5\x4a\x45\xd3\xc9\xa1\xd2\xad\x2a\xb6\x6d\x64\x81\x82\x6f\xa8\xd7\x32\xf4\x80\xcd\x4e\x2c\xf8\x98\x23\x8d\x1d\xce\xbf\xa4\xe5\xba\xc5\x8e\x97\x4f\xd1\x10\xbb\x98\xb6\xc8\x7e\x07\xab\x29\x35\xc0\xc5\x88\x41\x0a\xef\x6e\x3e\xbc\x8f\x35\x33\xb6\x8d\x36\x06\xba\x8d\x70\x67\x71\xf3\x35\xfb\x33\xe5\xf6\x39\x9c\x82\x1e\xc8\x8f\x78\x9f\xee\xcc\x3f\x4e\x31\x66\x5a\x1f\x3f\x9c\x93\x19\xb4\x9c\x4f\xd9\x38\xb2\x95\x29\x27\x90\xb3\xb2\xbc\x65\xf9\xd7\x7d\x1f\xee\x55\x3c\xdd\x95\x1b\x22\xdd\x64\x6b\x3f\x20\x05\x89\x8f\xf0\xe9\x7a\xb9\x20\xd2\x1f\xf1\xbe\x42\x4b\xd1\x68\x70\x27\x56\xd2\x20\x2b\xb6\x7e\x50\xf9\x86\x49\x8e\x90\xc2\x60\xa4\xad\x57\x07\x63\x2f\xf2\x12\xdf\xa8\x03\xf3\x0c\xce\xce\xba\x7c\x07\x5e\x17\xd3\xe9\x41\x0d\x5d\xd7\xbd\x24\x56\x2b\x69\x71\x85\x4f\xfb\x55\xdc\x83\xa8\x87\x2d\x6a\x94\x51\xf8\xe6\x6a\xe5\x16\xc9\x01\x20\x53\xe1\x91\x0d\x8b\xb2\xe8\x43\x3b\x22\xd9\xdf\xee\x60\x9e\x34\x3f\x35\x59\xf0\x2b\x00\x00\xff\xff\x43\xde\x7f\x32\x51\x05\x00\x00")
+var _templatesAppHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x9c\x56\xcd\x72\xdb\x46\x12\xbe\xe3\x29\x7a\x71\xf0\x52\x2a\x2d\x40\xbb\xec\x8b\x04\x62\x77\x25\xcb\x51\x52\x94\xad\x92\xe9\x72\x2a\xb7\x11\xd0\x04\x26\x06\x67\xc6\x33\x0d\x53\x0c\x8b\x2f\x92\x5b\x5e\x2d\x4f\x92\x9a\x1f\x82\x20\x48\xa5\xe4\x1c\x5c\xc6\x74\x4f\x77\x7f\xdf\xd7\xdd\x43\x65\xf5\xcb\xfc\xcf\xdf\xff\xf8\x85\x29\xf8\x0f\xac\xd7\x90\x5c\x49\x31\xe7\x55\x72\x23\x0d\xc1\x66\x93\xa5\xf5\xcb\x3c\x8a\x32\x95\x7f\x24\x46\xad\x39\x87\xcc\x28\x26\x80\x97\x93\xd8\x38\x4b\x9c\xdb\x28\xef\x75\x01\xd6\x9f\x67\xa9\xca\xa3\x68\xbd\x06\x3e\x07\xfc\xda\xf9\xe3\x25\xe3\xc4\x45\x15\xc3\x66\x13\xbc\xc9\x5b\x54\x28\x4a\x14\xc5\xea\x5a\x6b\xa9\xad\x27\x53\xf9\xac\x46\x60\x4a\x41\x21\xdb\xa6\x14\xff\x26\x30\xc4\x34\x9d\x39\x88\x87\x11\x09\x64\x0c\x6a\x8d\xf3\x49\x9c\xfe\xc6\x54\x9c\xcf\xf4\x0a\x58\xc5\xb8\xc8\x52\xe6\xc1\xac\xd7\x80\x8d\xc1\x90\xfe\xb3\xc7\x01\x73\xa9\x7b\xa1\xa9\xcd\x1e\x5c\xef\x5c\x62\x9f\xed\xc0\x6c\xb3\xc2\xa8\x67\xee\xf8\x9f\x00\x49\x8f\x35\xe9\xca\x8a\x32\xd0\x0d\x5f\xf6\x73\xc9\xa9\xb6\xb2\x48\x75\x8f\xcc\x48\x11\x70\x4d\x99\xb1\x54\xa5\x52\x58\x9e\x3b\xb2\xae\x9a\xca\x8f\x86\xfb\x5e\x0d\x65\x2b\x9c\x75\xa7\xdc\x03\x82\x46\x56\x7a\xbc\x16\xe2\x19\x50\x50\x97\x1b\x30\xc4\x9b\x06\x74\x2b\x84\xd5\xc3\xe5\xb5\x5e\xa5\xf1\x1b\x97\xad\x09\xd9\x92\x27\x31\x5c\xb6\xbc\x29\x87\x10\x1a\x4b\xe3\xc1\x7a\x60\xce\x78\x83\xcf\x2b\xbe\x57\xd7\x45\x27\xf0\x11\xd1\xd9\xf7\xfa\x9b\x36\xb2\xfa\xaf\xd2\xb2\x40\x63\x26\xee\x62\x9c\xbb\xff\x40\xb6\xa4\x5a\xb2\xed\x39\x86\x78\x30\x8c\x85\x66\xa6\x6e\xa4\x54\xf1\x60\xe8\xf0\x91\x13\x96\xb0\x5e\x43\x83\x02\x92\xeb\x47\x4e\xb6\xb7\x40\x7c\x81\x06\x34\x16\x28\xa8\x59\x9d\x01\x27\x58\xca\x4e\x62\xd7\x75\x2c\xa1\x15\xc4\x1b\xbf\x4b\xb6\xc0\x54\x4a\xf5\xc9\x9a\x92\x77\x52\x2f\x18\x41\xfc\xf2\xcd\xf9\xf8\xf5\xf9\xf8\x4d\x7c\x38\xb9\x69\x48\x13\xe7\xf7\xfe\x03\x84\x5c\xee\xcd\xf0\x50\xff\xcf\x4c\x5b\xf1\x8c\xa7\xa0\x11\x8a\x86\x19\x33\x89\x97\xc1\xee\xa6\x57\x33\x51\xa1\xd3\x3f\xb4\x61\x97\x2a\x4b\x95\xc6\xe3\xa9\xef\x65\x4b\xd8\x4d\x76\x94\x11\x7b\x68\x30\x8f\x00\x32\xd2\x79\x46\x75\xee\x2e\x64\x29\xd5\xee\x74\xc9\x8a\x2f\x28\xca\xee\xec\x23\xfd\x31\x25\x6d\x03\xf7\xa0\x74\x89\x4a\xb7\x60\x77\x8c\x6a\x87\x87\xca\xce\x36\x63\xba\x42\x1a\x5a\x7b\x8f\x0d\x95\xfd\xdc\x81\x41\x96\x06\xa4\xc7\x48\xdd\xf9\xb1\xc1\x27\x28\x05\xf7\x51\x12\xd6\xcd\x77\xfc\x42\x87\x9e\xc7\xf0\xe9\xf9\xb5\x8c\xde\xb3\x05\xc2\x66\x13\xe7\xbd\x83\xef\xfa\xd3\xb4\xb7\xb2\xf1\x72\x68\xda\x02\xfb\x87\x0a\xdd\x22\x69\x5e\x3c\xa1\xcf\x8c\x2f\x76\x1d\xbf\xba\xfb\xd4\x7d\xdf\xe2\x42\xea\x55\x77\xfc\xa0\x50\xc0\x9c\x37\xb8\xd3\x6e\x56\xdb\x67\xa8\xa7\xe5\xb6\x13\xcf\x1e\x11\x5b\xfc\xe8\x16\xed\xd1\xbf\xba\xfb\x34\x34\x79\x70\x43\xeb\xbb\xb7\x07\x6a\x06\x8c\x07\x22\xf7\x86\xe6\xfb\x24\xe5\xf3\xed\x33\x7d\x55\x5b\x5a\x61\x4f\x73\x6f\x03\x8d\x8d\x64\x25\x96\xc0\xa8\xf7\xeb\xeb\xaf\x96\x4f\x50\x55\xb9\x5b\xf4\xde\x5e\x0f\x0b\x3c\x63\xc9\x6d\x06\xf7\x2b\xde\xc8\x2a\xce\x0f\xdf\xc6\xf0\x26\xc7\x21\xdb\xff\x4b\xa6\x08\x75\x72\x29\x25\x4d\x65\xe5\xad\xe1\xf7\xd4\xfa\xa7\xb2\x9a\x31\xde\x04\x7b\xbf\x66\x94\x99\x42\x73\x45\x40\x2b\x85\x93\x98\xf0\x91\xd2\x5f\xd9\x37\xe6\xad\xb1\x15\x31\x3d\x3d\x85\xff\x59\xf7\xda\x90\xe6\xa2\xda\xc0\xe9\x69\x1a\x01\x7c\x63\x1a\xfc\x1f\x19\x30\x81\x78\x6f\x05\xe2\x8b\x28\x02\x98\xb7\xa2\x20\x2e\x05\xcc\x91\x8a\x7a\x2a\xab\xd1\x09\xac\x23\x00\xb0\x6c\x46\x21\xf4\x5f\x93\x1d\x9b\xad\x1b\xc0\x20\xd9\x69\x92\x2d\x8d\xba\x2c\x36\x1a\x2a\xa4\x91\x5f\x52\xa6\xb8\x5d\xd4\xf8\x0c\x5a\x55\x32\xc2\xa9\xac\x4e\x2e\x60\x73\x06\x6f\xc6\xe3\x93\x0b\x97\x67\x13\xd9\x7f\x7d\x24\xdd\xd5\x51\xc9\x88\x6d\xeb\x95\xb2\x68\x17\x28\x28\xa9\x90\xae\x1b\xb4\x9f\x97\xab\x1f\xcb\x91\x53\xff\x24\xe1\x42\xa0\xbe\x99\xdd\x4e\x61\x02\x36\xec\x62\xc8\x61\x32\x81\xd8\xed\xf4\x3e\x89\x25\x17\xa5\x5c\x26\xa6\xd0\xb2\x69\x66\x72\x34\x3e\xdb\x55\x7a\x90\xe5\x2a\x78\x6e\x90\x57\x35\xf5\x20\x43\x4f\xb0\x8b\x03\x0a\xce\x67\x95\xc6\x4e\xce\x67\xaa\x65\xd1\x62\xa7\x97\x4b\xe1\x15\x7b\x35\x1e\x8f\x8f\x54\xea\xdd\xdb\x97\x8b\x11\x83\x09\xfc\xf4\xf1\xc3\xfb\x44\x31\x6d\x82\xd7\x13\xe8\x26\xc2\xda\x12\x7f\xba\xf8\x7b\x95\xfd\xa5\x23\x42\xef\x85\x0f\xf4\x3e\x9c\x99\xef\xec\x62\xc2\x94\x1a\x2e\xce\x41\x0f\x82\xce\x87\xda\x58\x65\x5b\xdd\x9c\x41\xc1\x9a\xe6\x81\x15\x5f\xb6\x38\xec\x56\x3c\x2e\x9a\x9a\x48\xf9\x6c\xe1\x00\x13\x10\xb8\x84\x9f\x6f\xa7\x37\x44\xea\x1e\xbf\xb6\x68\x68\x74\xb2\x77\x27\x91\xc2\xbe\x6f\x2b\xd7\xa8\xc2\xbd\x17\x30\x81\xbd\x96\x06\xae\x56\x8c\x6d\x90\x0b\x71\x40\xad\x30\xaf\xe1\xc5\x8b\x2e\xdf\x4e\xaf\x57\xe3\xf1\x2e\x1a\x3a\xd4\xbd\x24\x46\x49\x61\x70\x86\x8f\xdb\x51\xdc\x0a\xb1\xd9\x87\xa8\x50\x8c\xe2\x1f\xae\x67\x76\x90\xac\x00\xa4\x5b\x1c\xd0\x30\x28\xca\xbe\x68\x03\x25\xfb\xd3\x1d\x65\xa9\x7f\x6a\xf2\xe8\xaf\x01\x00\xab\xdd\x55\xe5\xec\x0c\x00\x00")
 
 func templatesAppHtmlBytes() ([]byte, error) {
 	return bindataRead(
@@ -107,7 +107,7 @@ func templatesAppHtml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "templates/app.html", size: 1361, mode: os.FileMode(420), modTime: time.Unix(1570997864, 0)}
+	info := bindataFileInfo{name: "templates/app.html", size: 3308, mode: os.FileMode(420), modTime: time.Unix(1570997864, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -132,7 +132,7 @@ func templatesLayoutHtml() (*asset, error) {
 	return a, nil
 }
 
-var _templatesLogHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x74\x53\x4f\x6f\x9b\x4e\x10\xbd\xf3\x29\x9e\x38\x44\xe0\x9f\x65\xac\x9f\xda\x93\x8d\x55\x45\x8a\x9a\x4a\xce\xa5\xe1\xd0\x1e\x37\x30\x60\xda\xf5\x2e\xdd\x1d\x12\xbb\x16\xdf\xbd\x5a\x58\x6c\xec\xa6\x37\x98\x3f\xef\xbd\x79\x33\xbb\x6e\x0c\xa1\x2e\xd2\x50\xea\x2a\xdc\x9c\x4e\x58\x6c\x75\x95\x89\x5a\xa2\xeb\xd6\x49\x63\x68\x13\x04\x6b\x9b\x9b\xba\x61\xf0\xb1\xa1\x34\x64\x3a\x70\xf2\x43\xbc\x8a\x21\x1a\x6e\x02\x20\x99\xcd\xf0\xc9\xa5\x4f\x2f\x5a\x4b\x12\xaa\xc3\x6c\x96\x04\xc0\xab\x30\x28\x6b\x63\x19\x29\xd8\xb4\xb4\x0a\x02\xa0\x6c\x55\xce\xb5\x56\x28\x89\xf3\xdd\x56\x57\x51\x8c\x53\x00\x00\x96\x38\xab\xf7\xa4\x5b\x8e\xce\x45\x2e\x89\x8a\x38\x0a\x93\xdf\xa2\x49\x44\x53\x27\x4e\xeb\x1c\x6d\x53\x08\xa6\xad\xae\xe2\x15\xba\x39\x3e\x2e\x97\xf1\x2a\x00\xba\x2b\x8a\x73\x51\x54\x08\x16\x23\xcf\x3f\xf5\x0e\x8a\x6d\x6e\xb4\x94\x48\x61\x77\xba\x95\xc5\x73\xff\x1b\xc5\xbd\x78\xa0\xd0\x79\xbb\x27\xc5\x8b\x8a\xf8\x41\x92\xfb\xbc\x3f\x7e\x29\xa2\xde\xc2\x78\x51\x2b\x45\xe6\x31\x7b\xda\x22\x85\xe3\xf4\x5d\x75\x89\x68\xc0\x1d\x45\xc0\xf3\x64\xfa\x5e\x33\xeb\x7d\xd4\xcb\xf7\x03\x60\x62\xce\xdf\x53\x5d\xeb\xf2\x78\x8e\xa1\xf7\xfa\x42\x30\x5a\x5f\x0a\x69\x69\xe5\x83\x86\xb8\x35\xca\xaf\x63\x20\x9c\x84\xa3\xb7\x5a\x15\xfa\xcd\x8f\x41\x75\xb5\x63\xfc\x07\x1f\x1c\x04\x7f\x8f\xb1\x49\x2f\x36\xbc\xe8\xe2\xb8\xd0\x65\x69\x89\x87\xfa\x77\xf4\xde\x0c\xea\x05\x5e\xa1\x66\x3a\x5a\xce\x6f\x50\x87\xcc\x80\xfa\x8e\x0d\xee\x2a\x5a\x23\xe7\xc8\x85\x94\x2f\x22\xff\x39\x02\xbb\x25\x1e\xf6\x72\xc7\xdc\x0c\x23\xfa\x1f\xa4\x50\xf4\x86\x6f\x4f\xdb\x47\xe6\xe6\x2b\xfd\x6a\xc9\xf2\xe8\xbb\xaf\x59\x68\x65\x48\x14\x47\xcb\x82\x29\xdf\x09\x55\x91\x73\x70\x7a\x8e\xde\x48\x67\xf8\xd8\xd4\xb7\x3c\xbb\x16\xa4\x29\x3e\xe0\xee\xee\x8c\xe7\x80\x5a\xeb\xc2\xff\x2f\x97\x97\x6e\x9c\x55\x4f\x40\x6c\xa3\x95\xa5\x8c\x0e\x1c\x8f\xeb\xea\x26\x3b\x3a\x4b\x6c\x48\x45\xe1\xe7\x87\xcc\x3d\x03\x67\x80\x5b\xe6\xcd\x18\x96\x54\x31\xbd\x9d\xc9\x39\xad\x93\xe1\xed\x6e\x82\x3f\x01\x00\x00\xff\xff\x5c\x6f\x05\xa1\xff\x03\x00\x00")
+var _templatesLogHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x8c\x54\xc1\x6e\xdb\x3a\x10\xbc\xeb\x2b\x16\x7c\x87\x27\x3b\x86\xd4\x5b\x80\xc6\x72\x8a\x04\x01\x5a\x20\x28\x0a\x24\x97\xa2\xed\x81\x11\x57\x32\x0b\x9a\xab\x92\xab\x38\xaa\xa0\x7f\x2f\x48\xca\xb1\x93\xf4\xd0\x9b\xc9\x9d\x9d\x9d\xe1\xac\xb5\xf6\x3c\x18\xdc\x64\x00\x85\x67\x85\xce\x8d\x35\x19\x72\xef\xff\x6b\x9a\x73\x79\x2e\xa7\x6c\x5d\xce\x88\x6c\xdd\x39\x04\xad\x2a\x61\xa8\x15\x9b\x71\x04\x27\x6d\x8b\x50\xdc\x6a\x8b\x1e\xa6\x69\xed\x3b\x69\xa1\x36\xd2\xfb\x4a\x8c\x23\x14\x77\xec\x50\xee\x60\x9a\x22\x5a\x37\x20\xad\x82\xe2\x8b\xa3\x1a\xbd\x87\xdc\xe2\xf1\x20\xf6\xf8\x20\x16\x30\x4d\xdf\xc6\xf1\x78\x3b\x4d\x3f\x60\x1c\x01\xad\x82\x69\x0a\x85\x7b\x7c\xe2\x30\xa9\x0c\xa3\x36\xcf\xa5\x75\xd9\xb9\xa8\xd0\xd7\x4e\x77\x0c\x3c\x74\x58\x09\xc6\x27\x2e\x7f\xca\x47\x99\x6e\x45\x30\xf9\x28\x1d\x18\x6a\xa1\x02\x45\x75\xbf\x43\xcb\x45\x8b\x7c\x63\x30\xfc\xbc\x1a\x3e\xa9\x3c\xba\x5b\x5c\x64\x19\x40\x59\x42\x43\xc6\xd0\xde\x83\xc5\x7d\xec\x33\xd1\xab\xf4\xc0\x5b\x1c\xfe\x77\x08\x7b\xa7\x99\xd1\xae\x40\xda\x01\x7e\xf5\xe8\x06\xf0\xec\xb4\x6d\x21\xc7\xb6\x80\xcb\x46\x1b\x46\x57\xad\x12\xdd\xa5\x8f\x4f\x52\x01\x39\xb8\xec\x92\xcb\x6a\x01\xda\x43\x27\xbd\x47\x05\xbc\x75\xd4\xb7\x5b\x60\x0a\x13\x20\xc1\x33\x80\xa6\xb7\x35\x6b\xb2\xb3\xa0\x7c\x01\x63\x06\x90\xfc\x78\xea\x5d\x8d\x50\x45\x91\x37\x8f\x68\xf9\x2e\xde\xe4\xa2\xfc\x2d\xbb\x52\x76\xba\x34\xd4\x96\x89\x4b\xc0\x19\xec\xb5\x55\xb4\x2f\x0c\xd5\x32\x70\x16\x1e\xa5\xab\xb7\x8b\x8b\xc8\x98\xd8\x0a\xa9\x54\xa4\xba\xd5\x9e\xd1\xa2\xcb\x85\x67\x45\x3d\x8b\x15\xc8\xae\x43\xab\xfe\x01\x8e\xce\xbd\x84\x4f\xd9\xa9\x95\x54\xc8\x31\xf4\x1d\xfc\x94\xcb\x25\x7c\x08\xf1\x8d\x0f\x44\x06\xa5\x9d\x60\xb9\x2c\x8f\x56\x6b\x47\xc6\x40\x05\x7e\x4b\xbd\x51\x77\xf1\x98\xa7\xb4\xe6\x70\xb5\xc5\xd3\x74\x6b\x87\x92\x71\x0e\x38\x17\x61\x6f\xc4\x2c\x3d\x40\x8b\xb8\xae\x9f\xe5\x2e\x34\x45\x25\x45\x98\x7e\x02\x08\x5b\x74\x4d\x96\xd1\xf2\x33\x44\x49\x96\x70\x06\xe2\xbb\x15\x33\x92\xda\x22\xd9\xb9\xde\x6a\xa3\xf2\xd0\x79\x50\xa5\x1b\xc8\x93\xee\x83\x49\x98\x7d\xdc\xd3\x15\x31\xd3\x2e\x9f\x05\x4d\x6f\x9e\xe8\xa5\xcd\xb9\xdd\x21\xf7\xce\x42\x3e\xc7\xa8\xad\x45\xf7\x11\x75\xbb\xe5\x63\xb6\x69\xc0\xd7\x05\x6c\x4e\xde\xe2\x81\xd4\x50\x50\xd3\x78\xe4\x84\x7f\x9b\xc9\x6b\x61\xf3\xc8\x17\xac\xf7\x94\xbf\x5b\xbd\x62\x4d\x95\xc4\x7a\x8c\xfa\x6f\x36\x0f\x0b\x7c\x11\xbe\x2c\xf1\x8f\xb9\xc9\xfe\x0c\x00\x94\xdd\xe7\x50\x85\x04\x00\x00")
 
 func templatesLogHtmlBytes() ([]byte, error) {
 	return bindataRead(
@@ -147,7 +147,7 @@ func templatesLogHtml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "templates/log.html", size: 1023, mode: os.FileMode(420), modTime: time.Unix(1570997864, 0)}
+	info := bindataFileInfo{name: "templates/log.html", size: 1157, mode: os.FileMode(420), modTime: time.Unix(1570997864, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -0,0 +1,125 @@
+package zap
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/puma/puma-dev/homedir"
+)
+
+// configWatcher polls the apps directory for config files that have been
+// added, changed or removed and applies the changes to the running apps
+type configWatcher struct {
+	Path     string
+	Interval time.Duration
+
+	files map[string]time.Time
+	done  chan struct{}
+}
+
+func newConfigWatcher(path string) *configWatcher {
+	return &configWatcher{
+		Path:     homedir.MustExpand(path),
+		Interval: 2 * time.Second,
+		done:     make(chan struct{}),
+	}
+}
+
+// Watch polls the directory until Stop is called
+func (w *configWatcher) Watch() {
+	files, err := w.scan()
+	if err != nil {
+		log.Println("[watcher]", "unable to read", w.Path, err)
+	}
+	w.files = files
+
+	log.Println("[watcher]", "watching", w.Path, "for config changes")
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			files, err := w.scan()
+			if err != nil {
+				log.Println("[watcher]", "unable to read", w.Path, err)
+				continue
+			}
+			if w.changed(files) {
+				w.files = files
+				reloadApps()
+			}
+		}
+	}
+}
+
+// Stop stops watching the directory
+func (w *configWatcher) Stop() {
+	close(w.done)
+}
+
+func (w *configWatcher) scan() (map[string]time.Time, error) {
+	files := map[string]time.Time{}
+
+	infos, err := ioutil.ReadDir(w.Path)
+	if err != nil {
+		return files, err
+	}
+
+	for _, info := range infos {
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") || info.Name() == configFile {
+			continue
+		}
+		files[info.Name()] = info.ModTime()
+	}
+
+	return files, nil
+}
+
+func (w *configWatcher) changed(files map[string]time.Time) bool {
+	if len(files) != len(w.files) {
+		return true
+	}
+	for name, modTime := range files {
+		previous, ok := w.files[name]
+		if !ok || !previous.Equal(modTime) {
+			log.Println("[watcher]", name, "changed")
+			return true
+		}
+	}
+	return false
+}
+
+// reloadApps re-resolves the config for every running app and restarts,
+// replaces or stops the app if its config has changed
+func reloadApps() {
+	appsMu.Lock()
+	running := []*app{}
+	for _, app := range apps {
+		running = append(running, app)
+	}
+	appsMu.Unlock()
+
+	for _, app := range running {
+		config, err := getAppConfig(app.config().Host)
+		if os.IsNotExist(err) {
+			log.Println("[watcher]", app.config().Host, "config removed", err)
+			app.Stop("config removed", err)
+			continue
+		}
+		app.setConfigError(err)
+		if err != nil {
+			log.Println("[watcher]", app.config().Host, "error reading config", err)
+			continue
+		}
+
+		if err := app.Reload(config); err != nil {
+			log.Println("[watcher]", app.config().Host, "error reloading app", err)
+		}
+	}
+}
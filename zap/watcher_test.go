@@ -0,0 +1,82 @@
+package zap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/puma/puma-dev/homedir"
+)
+
+func TestReloadAppsConfigError(t *testing.T) {
+	home, err := ioutil.TempDir("", "zap-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	homedir.DisableCache = true
+	defer func() { homedir.DisableCache = false }()
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", home)
+
+	dir := filepath.Join(home, ".zap")
+	os.Mkdir(dir, 0755)
+
+	path := filepath.Join(dir, "web.test")
+	write := func(config string) {
+		if err := ioutil.WriteFile(path, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("proxy: http://127.0.0.1:3000\n")
+	config, err := getAppConfig("web.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := newApp(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	appsMu.Lock()
+	apps = map[string]*app{config.Key: a}
+	appsMu.Unlock()
+	defer func() {
+		appsMu.Lock()
+		apps = nil
+		appsMu.Unlock()
+	}()
+
+	running := func() bool {
+		appsMu.Lock()
+		defer appsMu.Unlock()
+		return apps[config.Key] == a
+	}
+
+	write("proxy: [http://127.0.0.1:3000\n")
+	reloadApps()
+	if !running() {
+		t.Fatal("expected the app to keep running after a config parse error")
+	}
+	if a.ConfigError == "" {
+		t.Fatal("expected the config error to be recorded")
+	}
+	if a.config() != config {
+		t.Fatal("expected the previous config to be kept")
+	}
+
+	write("proxy: http://127.0.0.1:3000\n")
+	reloadApps()
+	if a.ConfigError != "" {
+		t.Fatal("expected the config error to be cleared, got", a.ConfigError)
+	}
+
+	os.Remove(path)
+	reloadApps()
+	if running() {
+		t.Fatal("expected the app to be stopped once its config is removed")
+	}
+}